@@ -0,0 +1,391 @@
+package s
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func isHex(b byte) bool {
+	return isDigit(rune(b)) || ('a' <= b && b <= 'f') || ('A' <= b && b <= 'F')
+}
+
+// octets returns the expression for a Rivest octet string, matching the
+// mapping used by ReadCanonical.
+func octets(bs []byte) Expression {
+	if isToken(bs) {
+		return Identifier(bs)
+	}
+	return Binary(bs)
+}
+
+// peekLength reports whether the digit just read starts the length prefix
+// of an atom such as 3:abc rather than a number.
+func (this *Reader) peekLength() bool {
+	for i := 0; ; i++ {
+		b, err := this.peekByte(i)
+		if err != nil {
+			return false
+		}
+		switch {
+		case isDigit(rune(b)):
+		case b == ':' || b == '"' || b == '#' || b == '|':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// peekHex reports whether the # just read starts a hexadecimal atom such as
+// #616263# rather than #t, #f or a #b binary.
+func (this *Reader) peekHex() bool {
+	for i := 0; ; i++ {
+		b, err := this.peekByte(i)
+		if err != nil {
+			return false
+		}
+		if b == '#' {
+			return i%2 == 0
+		} else if !isHex(b) {
+			return false
+		}
+	}
+}
+
+func (this *Reader) isAdvancedAtom(r rune) bool {
+	switch {
+	case r == '|' || r == '"' || r == '[':
+		return true
+	case r == '#':
+		return this.peekHex()
+	case isDigit(r):
+		return this.peekLength()
+	}
+	return false
+}
+
+// readUntil reads the bytes up to end, which is consumed.
+func (this *Reader) readUntil(start Position, token string, end byte) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := this.readByte()
+		if err != nil {
+			return nil, this.syntaxError(start, token, "Unterminated atom", err)
+		}
+		if b == end {
+			return buf.Bytes(), nil
+		}
+		if !isWhitespace(rune(b)) {
+			buf.WriteByte(b)
+		}
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readQuoted reads a quoted string using Rivest's escapes.
+func (this *Reader) readQuoted(start Position) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := this.readByte()
+		if err != nil {
+			return nil, this.syntaxError(start, `"`, "Unterminated string", err)
+		}
+		if b == '"' {
+			return buf.Bytes(), nil
+		}
+		if err = this.checkAtom(start, buf.Len()+1); err != nil {
+			return nil, err
+		}
+		if b != '\\' {
+			buf.WriteByte(b)
+			continue
+		}
+
+		escape := this.pos
+		b, err = this.readByte()
+		if err != nil {
+			return nil, this.syntaxError(start, `"`, "Unterminated string", err)
+		}
+		switch b {
+		case 'b':
+			buf.WriteByte('\b')
+		case 't':
+			buf.WriteByte('\t')
+		case 'v':
+			buf.WriteByte('\v')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'r':
+			buf.WriteByte('\r')
+		case '"', '\'', '\\':
+			buf.WriteByte(b)
+		case '\n', '\r':
+			// line continuation, \r\n and \n\r count as one line break
+			if c, err := this.peekByte(0); err == nil && (c == '\n' || c == '\r') && c != b {
+				this.readByte()
+			}
+		case 'x', '0', '1', '2', '3', '4', '5', '6', '7':
+			base, digits := 8, []byte{b}
+			if b == 'x' {
+				base, digits = 16, nil
+			}
+			for len(digits) < 2 || (base == 8 && len(digits) < 3) {
+				d, err := this.readByte()
+				if err != nil {
+					return nil, this.syntaxError(start, `"`, "Unterminated string", err)
+				}
+				digits = append(digits, d)
+			}
+			v, err := strconv.ParseUint(string(digits), base, 8)
+			if err != nil {
+				return nil, this.syntaxError(escape, "\\"+string(b), "Invalid escape", nil)
+			}
+			buf.WriteByte(byte(v))
+		default:
+			return nil, this.syntaxError(escape, "\\"+string(b), "Invalid escape", nil)
+		}
+	}
+}
+
+// readAdvancedAtom reads the atoms of Rivest's advanced syntax: verbatim
+// 3:abc, quoted "abc", hexadecimal #616263# and base64 |YWJj|, each with an
+// optional length prefix, and [hint]atom display hints. Atoms hinted other
+// than [text/plain] are read as Binary without their hint.
+func (this *Reader) readAdvancedAtom(start Position, r rune) (Expression, error) {
+	length := -1
+	if isDigit(r) {
+		length = int(r - '0')
+		for {
+			b, err := this.readByte()
+			if err != nil {
+				return nil, this.syntaxError(start, "", "Truncated atom", err)
+			}
+			if !isDigit(rune(b)) {
+				r = rune(b)
+				break
+			}
+			if length > 1<<30 {
+				return nil, this.syntaxError(start, "", "Invalid atom length", nil)
+			}
+			length = length*10 + int(b-'0')
+		}
+	}
+
+	var bs []byte
+	var err error
+	switch r {
+	case ':':
+		// verbatim
+		if err := this.checkAtom(start, length); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for i := 0; i < length; i++ {
+			b, err := this.readByte()
+			if err != nil {
+				return nil, this.syntaxError(start, "", "Truncated atom", err)
+			}
+			buf.WriteByte(b)
+		}
+		return octets(buf.Bytes()), nil
+	case '"':
+		bs, err = this.readQuoted(start)
+	case '#':
+		bs, err = this.readUntil(start, "#", '#')
+		if err == nil {
+			bs, err = hex.DecodeString(string(bs))
+			if err != nil {
+				err = this.syntaxError(start, "#", "Invalid hexadecimal atom", nil)
+			}
+		}
+	case '|':
+		bs, err = this.readUntil(start, "|", '|')
+		if err == nil {
+			bs, err = base64.StdEncoding.DecodeString(string(bs))
+			if err != nil {
+				err = this.syntaxError(start, "|", "Invalid base64 atom", nil)
+			}
+		}
+	case '[':
+		hint, err := this.readExpression()
+		if err != nil {
+			return nil, this.syntaxError(start, "[", "Missing display hint", err)
+		}
+		b, err := this.readByte()
+		if err != nil || b != ']' {
+			return nil, this.syntaxError(start, "[", "Unterminated display hint", err)
+		}
+		var name string
+		switch hint.(type) {
+		case Identifier, String, Binary:
+			hint.Scan(&name)
+		default:
+			return nil, this.syntaxError(start, fmt.Sprint(hint), "Unsupported display hint", nil)
+		}
+		exp, err := this.readExpression()
+		if err != nil {
+			return nil, this.syntaxError(start, "[", "Missing hinted atom", err)
+		}
+		if err = exp.Scan(&bs); err != nil {
+			return nil, this.syntaxError(start, "[", "Invalid hinted atom", nil)
+		}
+		if name != textHint {
+			// other hints such as [image/gif] are dropped
+			return Binary(bs), nil
+		}
+		return String(bs), nil
+	default:
+		return nil, this.syntaxError(start, "", "Invalid atom length", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if length >= 0 && length != len(bs) {
+		return nil, this.syntaxError(start, "", "Atom length mismatch", nil)
+	}
+	if r == '"' {
+		return String(bs), nil
+	}
+	return octets(bs), nil
+}
+
+// readTransport reads the {base64} transport encoding of a canonical
+// expression.
+func (this *Reader) readTransport(start Position) (Expression, error) {
+	encoded, err := this.readUntil(start, "{", '}')
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, this.syntaxError(start, "{", "Invalid transport encoding", nil)
+	}
+	rdr := NewReader(bytes.NewReader(decoded))
+	rdr.Limits, rdr.depth = this.Limits, this.depth
+	exp, err := rdr.readCanonical()
+	if err == nil {
+		if bs, _ := rdr.Peek(1); len(bs) > 0 {
+			err = fmt.Errorf("Unexpected data after expression")
+		}
+	}
+	if err != nil {
+		return nil, &SyntaxError{Position: start, Token: "{", Msg: "Invalid transport encoding", Err: err}
+	}
+	return exp, nil
+}
+
+func quoteAdvanced(bs []byte) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, b := range bs {
+		switch b {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\v':
+			buf.WriteString(`\v`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if b < 0x20 || b >= 0x7F {
+				buf.WriteString(`\x` + hex.EncodeToString([]byte{b}))
+			} else {
+				buf.WriteByte(b)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// compactAtom returns the shortest encoding of bs which reads back as the
+// same octets. Quoted strings are left out as they read back as String.
+func compactAtom(bs []byte) string {
+	candidates := []string{
+		strconv.Itoa(len(bs)) + ":" + string(bs),
+		"#" + hex.EncodeToString(bs) + "#",
+		"|" + base64.StdEncoding.EncodeToString(bs) + "|",
+	}
+	if isToken(bs) {
+		candidates = append([]string{string(bs)}, candidates...)
+	}
+	shortest := candidates[0]
+	for _, c := range candidates[1:] {
+		if len(c) < len(shortest) {
+			shortest = c
+		}
+	}
+	return shortest
+}
+
+// WriteAdvanced writes exp in Rivest's advanced syntax, choosing the most
+// compact encoding for each atom. The output can be read back by a Reader
+// with Advanced set, though identifiers which need quoting read back as
+// String.
+func WriteAdvanced(exp Expression, dst io.Writer) error {
+	var err error
+	switch t := exp.(type) {
+	case List:
+		_, err = io.WriteString(dst, "(")
+		for i, e := range t.WithoutComments() {
+			if err == nil && i > 0 {
+				_, err = io.WriteString(dst, " ")
+			}
+			if err == nil {
+				err = WriteAdvanced(e, dst)
+			}
+		}
+		if err == nil {
+			_, err = io.WriteString(dst, ")")
+		}
+	case Binary:
+		_, err = io.WriteString(dst, compactAtom(t))
+	case Identifier:
+		// octet atoms which aren't tokens read back as Binary, so other
+		// identifiers are written as quoted strings
+		str := string(t)
+		if !isPlainIdentifier(str) {
+			str = quoteAdvanced([]byte(t))
+		}
+		_, err = io.WriteString(dst, str)
+	case String:
+		str := quoteAdvanced([]byte(t))
+		if hinted := "[" + textHint + "]" + compactAtom([]byte(t)); len(hinted) < len(str) {
+			str = hinted
+		}
+		_, err = io.WriteString(dst, str)
+	case Number, True, False:
+		err = t.Write(dst)
+	default:
+		err = fmt.Errorf("Cannot write %T in advanced form", exp)
+	}
+	return err
+}
+
+// WriteTransport writes exp in the {base64} transport encoding of its
+// canonical form.
+func WriteTransport(exp Expression, dst io.Writer) error {
+	var buf bytes.Buffer
+	err := WriteCanonical(exp, &buf)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(dst, "{"+base64.StdEncoding.EncodeToString(buf.Bytes())+"}")
+	return err
+}
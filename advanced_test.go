@@ -0,0 +1,103 @@
+package s
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReaderAdvanced(t *testing.T) {
+	type testCase struct {
+		input  string
+		result Expression
+	}
+	testCases := []testCase{
+		{`3:abc`, Identifier("abc")},
+		{"3:\x00\x01\x02", Binary{0, 1, 2}},
+		{`#616263#`, Identifier("abc")},
+		{`#00ff#`, Binary{0, 0xff}},
+		{`|AAE=|`, Binary{0, 1}},
+		{`3|AAEC|`, Binary{0, 1, 2}},
+		{`"a\tb\x41\101\"\` + "\n" + `c"`, String("a\tbAA\"c")},
+		{`3"abc"`, String("abc")},
+		{`[text/plain]4:a bc`, String("a bc")},
+		{`[image/gif]3:abc`, Binary("abc")},
+		{`(12 #t #f 1:a)`, NewList(Number("12"), True{}, False{}, Identifier("a"))},
+		{`{KDM6YWJjWzEwOnRleHQvcGxhaW5dMjpoaSk=}`, NewList(Identifier("abc"), String("hi"))},
+	}
+	for _, tc := range testCases {
+		rdr := NewReader(bytes.NewBufferString(tc.input))
+		rdr.Advanced = true
+		exp, err := Read(rdr)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %q", err, tc.input)
+			continue
+		}
+		if !reflect.DeepEqual(exp, tc.result) {
+			t.Errorf("Expected %#v got %#v for %q", tc.result, exp, tc.input)
+		}
+	}
+
+	for _, input := range []string{`4:abc`, `#abc#`, `|!|`, `2"abc"`, `"\q"`, `[image/png]1`, `[(a)]1:a`, `{YQ==}`} {
+		rdr := NewReader(bytes.NewBufferString(input))
+		rdr.Advanced = true
+		_, err := Read(rdr)
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Expected a SyntaxError got %v for %q", err, input)
+		}
+	}
+}
+
+func TestWriteAdvanced(t *testing.T) {
+	type testCase struct {
+		exp    Expression
+		result string
+	}
+	testCases := []testCase{
+		{Identifier("abc"), `abc`},
+		{Binary{0, 1, 2}, "3:\x00\x01\x02"},
+		{String("a\"b"), `"a\"b"`},
+		{String("\x00\x01\x02\x03\x04\x05"), "[text/plain]6:\x00\x01\x02\x03\x04\x05"},
+		{NewList(Number("1"), True{}, Identifier("a b")), `(1 #t "a b")`},
+		{Identifier("λ"), `λ`},
+		{Identifier("a|b"), `"a|b"`},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		err := WriteAdvanced(tc.exp, &buf)
+		if err != nil || buf.String() != tc.result {
+			t.Errorf("Expected %q got %q, %v for %v", tc.result, buf.String(), err, tc.exp)
+		}
+	}
+
+	var buf bytes.Buffer
+	exp := NewList(Identifier("abc"), String("hi"))
+	err := WriteTransport(exp, &buf)
+	if err != nil || buf.String() != `{KDM6YWJjWzEwOnRleHQvcGxhaW5dMjpoaSk=}` {
+		t.Errorf("Expected transport encoding got %v, %v", buf.String(), err)
+	}
+	encoded := buf.String()
+	rdr := NewReader(&buf)
+	rdr.Advanced = true
+	read, err := Read(rdr)
+	if err != nil || !reflect.DeepEqual(read, exp) {
+		t.Errorf("Expected %v got %v, %v", exp, read, err)
+	}
+	_, err = Read(bytes.NewBufferString(encoded))
+	if serr, ok := err.(*SyntaxError); !ok || serr.Msg != "Unknown token" {
+		t.Errorf("Expected transport to need Advanced got %v", err)
+	}
+
+	// identifiers read back as text, never as binary
+	for _, id := range []Identifier{"abc", "a b", "a|b", "\x01"} {
+		buf.Reset()
+		WriteAdvanced(id, &buf)
+		rdr = NewReader(&buf)
+		rdr.Advanced = true
+		read, err = Read(rdr)
+		var text string
+		if err != nil || read.Scan(&text) != nil || text != string(id) {
+			t.Errorf("Expected %q got %#v, %v", id, read, err)
+		}
+	}
+}
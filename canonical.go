@@ -0,0 +1,182 @@
+package s
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+const textHint = "text/plain"
+
+// isToken reports whether an atom can be written as an identifier. Tokens
+// are ASCII, as in Rivest's grammar.
+func isToken(bs []byte) bool {
+	for _, b := range bs {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return isPlainIdentifier(string(bs))
+}
+
+func writeCanonicalAtom(dst io.Writer, bs []byte) error {
+	_, err := io.WriteString(dst, strconv.Itoa(len(bs))+":")
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(bs)
+	return err
+}
+
+// WriteCanonical writes exp as a canonical S-expression: length-prefixed
+// atoms with no whitespace, so the same expression always produces the
+// same bytes. Strings are written with a [text/plain] display hint, other
+// atoms as plain octet strings. Comments are left out.
+func WriteCanonical(exp Expression, dst io.Writer) error {
+	var err error
+	switch t := exp.(type) {
+	case List:
+		_, err = io.WriteString(dst, "(")
+		for _, e := range t.WithoutComments() {
+			if err != nil {
+				break
+			}
+			err = WriteCanonical(e, dst)
+		}
+		if err == nil {
+			_, err = io.WriteString(dst, ")")
+		}
+	case Binary:
+		err = writeCanonicalAtom(dst, t)
+	case Identifier:
+		err = writeCanonicalAtom(dst, []byte(t))
+	case Number:
+		err = writeCanonicalAtom(dst, []byte(t))
+	case String:
+		_, err = io.WriteString(dst, "[")
+		if err == nil {
+			err = writeCanonicalAtom(dst, []byte(textHint))
+		}
+		if err == nil {
+			_, err = io.WriteString(dst, "]")
+		}
+		if err == nil {
+			err = writeCanonicalAtom(dst, []byte(t))
+		}
+	case True, False:
+		var buf bytes.Buffer
+		err = t.Write(&buf)
+		if err == nil {
+			err = writeCanonicalAtom(dst, buf.Bytes())
+		}
+	default:
+		err = fmt.Errorf("Cannot write %T in canonical form", exp)
+	}
+	return err
+}
+
+func (this *Reader) readCanonicalAtom() ([]byte, error) {
+	start := this.pos
+	n := 0
+	for digits := 0; ; digits++ {
+		b, err := this.readByte()
+		if err != nil {
+			return nil, this.syntaxError(start, "", "Unterminated atom", err)
+		}
+		if b == ':' && digits > 0 {
+			break
+		}
+		if !isDigit(rune(b)) || digits >= 18 || (digits == 1 && n == 0) {
+			return nil, this.syntaxError(start, "", "Invalid atom length", nil)
+		}
+		n = n*10 + int(b-'0')
+	}
+	if err := this.checkAtom(start, n); err != nil {
+		return nil, err
+	}
+
+	// the buffer grows as data arrives so a bogus length can't exhaust memory
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		b, err := this.readByte()
+		if err != nil {
+			return nil, this.syntaxError(start, "", "Truncated atom", err)
+		}
+		buf.WriteByte(b)
+	}
+	return buf.Bytes(), nil
+}
+func (this *Reader) readCanonical() (Expression, error) {
+	this.begin()
+	defer this.leave()
+	if err := this.enter(); err != nil {
+		return nil, err
+	}
+	start := this.pos
+	b, err := this.peekByte(0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b == '(':
+		this.readByte()
+		lst := List{}
+		for {
+			b, err = this.peekByte(0)
+			if err != nil {
+				return nil, this.syntaxError(start, "(", "Unterminated list", err)
+			}
+			if b == ')' {
+				this.readByte()
+				return lst, nil
+			}
+			exp, err := this.readCanonical()
+			if err != nil {
+				return nil, err
+			}
+			lst = append(lst, exp)
+			if err = this.checkList(start, len(lst)); err != nil {
+				return nil, err
+			}
+		}
+	case b == '[':
+		this.readByte()
+		hint, err := this.readCanonicalAtom()
+		if err != nil {
+			return nil, err
+		}
+		b, err := this.readByte()
+		if err != nil || b != ']' {
+			return nil, this.syntaxError(start, "[", "Unterminated display hint", err)
+		}
+		atom, err := this.readCanonicalAtom()
+		if err != nil {
+			return nil, err
+		}
+		if string(hint) != textHint {
+			// other hints such as [image/gif] are dropped
+			return Binary(atom), nil
+		}
+		return String(atom), nil
+	case isDigit(rune(b)):
+		atom, err := this.readCanonicalAtom()
+		if err != nil {
+			return nil, err
+		}
+		if isToken(atom) {
+			return Identifier(atom), nil
+		}
+		return Binary(atom), nil
+	}
+	return nil, this.syntaxError(start, string(b), "Unknown token", nil)
+}
+
+// ReadCanonical reads a canonical S-expression. Atoms with a [text/plain]
+// display hint are read as String, plain atoms which are valid identifiers
+// as Identifier and any other atom as Binary, dropping any other hint.
+func ReadCanonical(reader io.Reader) (Expression, error) {
+	return NewReader(reader).readCanonical()
+}
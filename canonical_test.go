@@ -0,0 +1,64 @@
+package s
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	type testCase struct {
+		exp    Expression
+		result string
+		read   Expression
+	}
+	testCases := []testCase{
+		{Identifier("abc"), `3:abc`, Identifier("abc")},
+		{Binary{0, 1, 2}, "3:\x00\x01\x02", Binary{0, 1, 2}},
+		{Binary("abc"), `3:abc`, Identifier("abc")},
+		{String("hello world"), `[10:text/plain]11:hello world`, String("hello world")},
+		{Number("-12"), `3:-12`, Binary("-12")},
+		{True{}, `2:#t`, Binary("#t")},
+		{
+			NewList(Identifier("sig"), NewList(), Comment(" x"), Binary{}),
+			`(3:sig()0:)`,
+			NewList(Identifier("sig"), List{}, Binary(nil)),
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		err := WriteCanonical(tc.exp, &buf)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.exp)
+			continue
+		}
+		if buf.String() != tc.result {
+			t.Errorf("Expected %q got %q for %v", tc.result, buf.String(), tc.exp)
+		}
+		exp, err := ReadCanonical(&buf)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %q", err, tc.result)
+			continue
+		}
+		if !reflect.DeepEqual(exp, tc.read) {
+			t.Errorf("Expected %#v got %#v for %q", tc.read, exp, tc.result)
+		}
+	}
+
+	for _, input := range []string{`(3:abc`, `4:abc`, `03:abc`, `3abc`, `[5:image`, `[5:image]3:ab`, `(a)`, ` 1:a`} {
+		_, err := ReadCanonical(bytes.NewBufferString(input))
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Expected a SyntaxError got %v for %q", err, input)
+		}
+	}
+
+	exp, err := ReadCanonical(bytes.NewBufferString(`([9:image/gif]3:abc)`))
+	if err != nil || !reflect.DeepEqual(exp, NewList(Binary("abc"))) {
+		t.Errorf("Expected (3:abc) got %v, %v", exp, err)
+	}
+
+	err = WriteCanonical(Pair{Identifier("a"), Identifier("b")}, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected an error writing a pair")
+	}
+}
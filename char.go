@@ -0,0 +1,84 @@
+package s
+
+import (
+	"bytes"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charNames are the names of characters written as #\name. Writing uses
+// the first name of a character.
+var charNames = []struct {
+	name string
+	char Char
+}{
+	{"alarm", '\a'},
+	{"backspace", '\b'},
+	{"delete", 0x7F},
+	{"escape", 0x1B},
+	{"newline", '\n'},
+	{"null", 0},
+	{"nul", 0},
+	{"return", '\r'},
+	{"space", ' '},
+	{"tab", '\t'},
+}
+
+// maxCharName is longer than any name or code point of a character.
+const maxCharName = 16
+
+func charName(c Char) (string, bool) {
+	for _, n := range charNames {
+		if n.char == c {
+			return n.name, true
+		}
+	}
+	return "", false
+}
+
+// readChar reads a character following #\: a single character, a name
+// such as space or a hexadecimal code point such as x41.
+func (this *Reader) readChar(start Position) (Char, error) {
+	r, err := this.readRune()
+	if err != nil {
+		return 0, this.syntaxError(start, `#\`, "Missing character", err)
+	}
+	// only a letter can start a name, so #\( is the character (
+	if !isLetter(r) {
+		return Char(r), nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteRune(r)
+	for {
+		r, err = this.readRune()
+		if err != nil {
+			break
+		}
+		if isDelimiter(r) {
+			this.unreadRune()
+			break
+		}
+		if buf.Len() > maxCharName {
+			return 0, this.syntaxError(start, `#\`+buf.String(), "Unknown character", nil)
+		}
+		buf.WriteRune(r)
+	}
+	name := buf.String()
+	if first, size := utf8.DecodeRuneInString(name); size == len(name) {
+		return Char(first), nil
+	}
+	for _, n := range charNames {
+		if n.name == name {
+			return n.char, nil
+		}
+	}
+	if name[0] == 'x' {
+		v, err := strconv.ParseUint(name[1:], 16, 32)
+		if err == nil && v <= unicode.MaxRune {
+			return Char(v), nil
+		}
+	}
+	return 0, this.syntaxError(start, `#\`+name, "Unknown character", nil)
+}
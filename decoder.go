@@ -0,0 +1,323 @@
+package s
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+type (
+	Decoder interface {
+		DecodeS(Expression) error
+	}
+	// DecodeOptions customises how expressions are unmarshalled.
+	DecodeOptions struct {
+		// Numbers controls numbers which don't fit their destination.
+		Numbers NumberMode
+		// Duplicates controls keys which appear more than once in a map.
+		Duplicates DuplicateKeys
+	}
+	// DuplicateKeys is how duplicate keys are handled when decoding maps.
+	DuplicateKeys int
+)
+
+const (
+	// LastKeyWins keeps the value of the last duplicate key
+	LastKeyWins DuplicateKeys = iota
+	// DuplicateKeyError returns an error for a duplicate key
+	DuplicateKeyError
+)
+
+var (
+	decoderType    = reflect.TypeOf((*Decoder)(nil)).Elem()
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	expressionType = reflect.TypeOf((*Expression)(nil)).Elem()
+	scalarTypes    = map[reflect.Kind]reflect.Type{
+		reflect.Bool:    reflect.TypeOf(false),
+		reflect.Int:     reflect.TypeOf(int(0)),
+		reflect.Int8:    reflect.TypeOf(int8(0)),
+		reflect.Int16:   reflect.TypeOf(int16(0)),
+		reflect.Int32:   reflect.TypeOf(int32(0)),
+		reflect.Int64:   reflect.TypeOf(int64(0)),
+		reflect.Uint:    reflect.TypeOf(uint(0)),
+		reflect.Uint8:   reflect.TypeOf(uint8(0)),
+		reflect.Uint16:  reflect.TypeOf(uint16(0)),
+		reflect.Uint32:  reflect.TypeOf(uint32(0)),
+		reflect.Uint64:  reflect.TypeOf(uint64(0)),
+		reflect.Uintptr: reflect.TypeOf(uintptr(0)),
+		reflect.Float32: reflect.TypeOf(float32(0)),
+		reflect.Float64: reflect.TypeOf(float64(0)),
+		reflect.String:  reflect.TypeOf(""),
+	}
+)
+
+func isEmptyList(src Expression) bool {
+	lst, ok := src.(List)
+	return ok && len(lst) == 0
+}
+
+func decodeInterface(src Expression) (interface{}, error) {
+	switch t := src.(type) {
+	case Pair:
+		src = NewList(t.Car, t.Cdr)
+	case Vector:
+		src = List(t)
+	}
+	lst, ok := src.(List)
+	if !ok {
+		var v interface{}
+		err := src.Scan(&v)
+		return v, err
+	}
+	lst = lst.WithoutComments()
+	vs := make([]interface{}, len(lst))
+	for i, exp := range lst {
+		v, err := decodeInterface(exp)
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+func (this DecodeOptions) decodeValue(src Expression, dst reflect.Value) error {
+	if lst, ok := src.(List); ok {
+		src = lst.WithoutComments()
+	}
+	// pairs decode like a list of their car and cdr, except into
+	// expressions and interfaces
+	if pair, ok := src.(Pair); ok {
+		switch dst.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Array:
+			if !reflect.TypeOf(src).AssignableTo(dst.Type()) {
+				src = NewList(pair.Car, pair.Cdr)
+			}
+		}
+	}
+
+	// expressions are stored as is
+	if dst.Type() == expressionType ||
+		(dst.Kind() != reflect.Interface && reflect.TypeOf(src).AssignableTo(dst.Type())) {
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	}
+
+	if dst.Kind() != reflect.Ptr && dst.CanAddr() && dst.Addr().Type().Implements(decoderType) {
+		return dst.Addr().Interface().(Decoder).DecodeS(src)
+	}
+
+	if dst.CanAddr() {
+		switch t := dst.Addr().Interface().(type) {
+		case *big.Int, *big.Float, *big.Rat:
+			if n, ok := src.(Number); ok {
+				return n.ScanMode(t, this.Numbers)
+			}
+			switch src.(type) {
+			case List, Pair:
+				return fmt.Errorf("Cannot unmarshal %T into %v", src, dst.Type())
+			}
+			return src.Scan(t)
+		}
+	}
+
+	// vectors decode like lists, as do bytevectors into anything but bytes
+	switch t := src.(type) {
+	case Vector:
+		src = List(t).WithoutComments()
+	case ByteVector:
+		if dst.Kind() == reflect.Array || dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() != reflect.Uint8 {
+			lst := make(List, len(t))
+			for i, b := range t {
+				lst[i] = Number(strconv.Itoa(int(b)))
+			}
+			src = lst
+		}
+	}
+
+	// empty lists are how nil pointers, maps, slices and interfaces are encoded
+	switch dst.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if isEmptyList(src) {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+	}
+
+	if dst.Type() == errorType {
+		var name, msg string
+		lst, ok := src.(List)
+		if !ok || len(lst) != 2 || lst.Scan(&name, &msg) != nil || name != "error" {
+			return fmt.Errorf("Cannot unmarshal %v into error", src)
+		}
+		dst.Set(reflect.ValueOf(errors.New(msg)))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			v, err := decodeInterface(src)
+			if err != nil {
+				return err
+			}
+			if v != nil {
+				dst.Set(reflect.ValueOf(v))
+			}
+			return nil
+		}
+		if e := dst.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() {
+			return this.decodeValue(src, e.Elem())
+		}
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return this.decodeValue(src, dst.Elem())
+	case reflect.Struct:
+		lst, ok := src.(List)
+		if !ok {
+			break
+		}
+		return this.decodeStruct(lst, dst)
+	case reflect.Map:
+		lst, ok := src.(List)
+		if !ok {
+			break
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		// keys seen in this list, as the map may already have some
+		var seen reflect.Value
+		if this.Duplicates == DuplicateKeyError {
+			seen = reflect.MakeMap(reflect.MapOf(dst.Type().Key(), reflect.TypeOf(true)))
+		}
+		for _, exp := range lst {
+			// entries are (key value) or (key . value)
+			var key, value Expression
+			switch entry := exp.(type) {
+			case List:
+				entry = entry.WithoutComments()
+				if len(entry) == 2 {
+					key, value = entry[0], entry[1]
+					break
+				}
+				// (key . (1 2)) and (key . ()) are read as (key 1 2) and (key)
+				switch dst.Type().Elem().Kind() {
+				case reflect.Slice, reflect.Array, reflect.Interface:
+					if len(entry) > 0 {
+						key, value = entry[0], entry[1:]
+						break
+					}
+					fallthrough
+				default:
+					return fmt.Errorf("Expected (key value) got %v", exp)
+				}
+			case Pair:
+				key, value = entry.Car, entry.Cdr
+			default:
+				return fmt.Errorf("Expected (key value) got %v", exp)
+			}
+			k := reflect.New(dst.Type().Key()).Elem()
+			err := this.decodeValue(key, k)
+			if err != nil {
+				return err
+			}
+			if seen.IsValid() {
+				if seen.MapIndex(k).IsValid() {
+					return fmt.Errorf("Duplicate key %v", key)
+				}
+				seen.SetMapIndex(k, reflect.ValueOf(true))
+			}
+			v := reflect.New(dst.Type().Elem()).Elem()
+			err = this.decodeValue(value, v)
+			if err != nil {
+				return err
+			}
+			dst.SetMapIndex(k, v)
+		}
+		return nil
+	case reflect.Slice:
+		switch t := src.(type) {
+		case Binary, String:
+			if dst.Type().Elem().Kind() != reflect.Uint8 {
+				break
+			}
+			var bs []byte
+			err := src.Scan(&bs)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(bs).Convert(dst.Type()))
+			return nil
+		case List:
+			dst.Set(reflect.MakeSlice(dst.Type(), len(t), len(t)))
+			for i, exp := range t {
+				err := this.decodeValue(exp, dst.Index(i))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Array:
+		lst, ok := src.(List)
+		if !ok {
+			break
+		}
+		for i := 0; i < dst.Len(); i++ {
+			if i >= len(lst) {
+				dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+				continue
+			}
+			err := this.decodeValue(lst[i], dst.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		typ, ok := scalarTypes[dst.Kind()]
+		// pairs scan by unmarshalling, so would never return here
+		switch src.(type) {
+		case List, Pair:
+			ok = false
+		}
+		if !ok {
+			break
+		}
+		tmp := reflect.New(typ)
+		var err error
+		if n, ok := src.(Number); ok {
+			err = n.ScanMode(tmp.Interface(), this.Numbers)
+		} else {
+			err = src.Scan(tmp.Interface())
+		}
+		if err != nil {
+			return err
+		}
+		dst.Set(tmp.Elem().Convert(dst.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("Cannot unmarshal %T into %v", src, dst.Type())
+}
+
+func Unmarshal(exp Expression, v interface{}) error {
+	return DecodeOptions{}.Unmarshal(exp, v)
+}
+
+// Unmarshal is like the package level Unmarshal, using these options.
+func (this DecodeOptions) Unmarshal(exp Expression, v interface{}) error {
+	if exp == nil {
+		return fmt.Errorf("Cannot unmarshal nil expression")
+	}
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	return this.decodeValue(exp, dst.Elem())
+}
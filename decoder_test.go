@@ -0,0 +1,218 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type (
+	decoderPoint struct {
+		X, Y int
+	}
+	decoderShape struct {
+		Name   string
+		Points []decoderPoint
+		Origin *decoderPoint
+		Tags   map[string]bool
+		Scale  [2]float64
+		Err    error
+		Data   []byte
+	}
+	decoderHook struct {
+		value string
+	}
+)
+
+func (this decoderHook) EncodeS() (Expression, error) {
+	return Identifier(this.value), nil
+}
+func (this *decoderHook) DecodeS(exp Expression) error {
+	return exp.Scan(&this.value)
+}
+
+func TestUnmarshal(t *testing.T) {
+	testCases := []interface{}{
+		true,
+		uint8(1),
+		int16(-1),
+		uint(7),
+		float64(-1.1),
+		"test",
+		[4]int{1, 2, 3, 4},
+		[]float64{1, 2, 3, 4},
+		[]string(nil),
+		map[string]int{"a": 1, "b": 2},
+		map[int][]string{1: {"x"}, 2: {"y", "z"}},
+		decoderPoint{25, 34},
+		&decoderPoint{1, 2},
+		[]*decoderPoint{{1, 2}, nil, {3, 4}},
+		decoderShape{
+			Name:   "triangle",
+			Points: []decoderPoint{{0, 0}, {1, 0}, {0, 1}},
+			Origin: &decoderPoint{5, 5},
+			Tags:   map[string]bool{"closed": true},
+			Scale:  [2]float64{0.5, 2},
+			Err:    errors.New("bad shape"),
+			Data:   []byte("abc"),
+		},
+		decoderHook{"hook"},
+		[]decoderHook{{"a"}, {"b"}},
+	}
+	for _, tc := range testCases {
+		exp, err := Encode(tc)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc)
+			continue
+		}
+		dst := reflect.New(reflect.TypeOf(tc))
+		err = Unmarshal(exp, dst.Interface())
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, exp)
+			continue
+		}
+		if !reflect.DeepEqual(dst.Elem().Interface(), tc) {
+			t.Errorf("Expected %#v got %#v for %v", tc, dst.Elem().Interface(), exp)
+		}
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	var v interface{}
+	err := Unmarshal(NewList(Number("1"), String("a"), NewList(True{})), &v)
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	expected := []interface{}{int64(1), "a", []interface{}{true}}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected %v got %v", expected, v)
+	}
+
+	var exp Expression
+	err = Unmarshal(Identifier("x"), &exp)
+	if err != nil || exp != Identifier("x") {
+		t.Errorf("Expected x got %v, %v", exp, err)
+	}
+
+	err = Unmarshal(String("x"), v)
+	if err == nil {
+		t.Errorf("Expected an error for a non-pointer destination")
+	}
+}
+
+func TestUnmarshalNumbers(t *testing.T) {
+	type testCase struct {
+		src      Number
+		dst      interface{}
+		expected interface{}
+		err      error
+	}
+	testCases := []testCase{
+		{"-1", new(uint8), uint8(0), ErrNumberSign},
+		{"3.14", new(uint8), uint8(3), ErrNumberFraction},
+		{"99999999999", new(uint8), uint8(255), ErrNumberRange},
+		{"-129", new(int8), int8(-128), ErrNumberRange},
+		{"1e400", new(float64), math.Inf(1), ErrNumberRange},
+		{"1e10001", new(float64), math.Inf(1), ErrNumberRange},
+		{"-1e10001", new(float32), float32(math.Inf(-1)), ErrNumberRange},
+		{"1e-10001", new(float64), 0.0, ErrNumberRange},
+		{"-1e-99999999999999999999", new(float64), 0.0, ErrNumberRange},
+		{"1e99999999999999999999", new(int8), int8(127), ErrNumberRange},
+		{"1e-10001", new(int), 0, ErrNumberRange},
+		{"1-2", new(int), 0, ErrNumberSyntax},
+		{"18446744073709551615", new(uint64), uint64(18446744073709551615), nil},
+		{"-2.5", new(big.Int), nil, ErrNumberFraction},
+		{"1.5", new(big.Int), nil, ErrNumberFraction},
+		{"-4/2", new(big.Int), big.NewInt(-2), nil},
+	}
+	for _, tc := range testCases {
+		for _, mode := range []NumberMode{NumberSaturate, NumberStrict} {
+			dst := reflect.New(reflect.TypeOf(tc.dst).Elem())
+			err := DecodeOptions{Numbers: mode}.Unmarshal(tc.src, dst.Interface())
+			expected := tc.err
+			// a big.Int fraction is an error in either mode
+			_, exact := tc.dst.(*big.Int)
+			if mode == NumberSaturate && expected != ErrNumberSyntax && !exact {
+				expected = nil
+			}
+			if !errors.Is(err, expected) || (err == nil) != (expected == nil) {
+				t.Errorf("Expected %v got %v for %v in mode %v", expected, err, tc.src, mode)
+				continue
+			}
+			var nerr *NumberError
+			if err != nil && !errors.As(err, &nerr) {
+				t.Errorf("Expected a NumberError got %T for %v", err, tc.src)
+			}
+			if err != nil {
+				continue
+			}
+			got := dst.Elem().Interface()
+			if dst.Type() == reflect.TypeOf(tc.expected) {
+				got = dst.Interface()
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v got %v for %v in mode %v", tc.expected, got, tc.src, mode)
+			}
+		}
+	}
+}
+
+func TestUnmarshalMaps(t *testing.T) {
+	exp, err := Read(bytes.NewBufferString(`((1 . "a") (2 "b") #;(3 "c") (4 . ("d")))`))
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	var m map[int]string
+	err = Unmarshal(exp, &m)
+	expected := map[int]string{1: "a", 2: "b", 4: "d"}
+	if err != nil || !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected %v got %v, %v", expected, m, err)
+	}
+
+	// list values of dotted entries are spliced into the entry by the reader
+	exp, err = Read(bytes.NewBufferString(`((a . (1 2)) (b . ()) (c (3)))`))
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	var lists map[string][]int
+	err = Unmarshal(exp, &lists)
+	if err != nil || !reflect.DeepEqual(lists, map[string][]int{"a": {1, 2}, "b": nil, "c": {3}}) {
+		t.Errorf("Expected lists got %v, %v", lists, err)
+	}
+	var values map[string]interface{}
+	err = Unmarshal(exp, &values)
+	if _, ok := values["b"]; err != nil || !ok || fmt.Sprint(values["a"]) != "[1 2]" {
+		t.Errorf("Expected lists got %v, %v", values, err)
+	}
+
+	var scanned map[float64]bool
+	err = NewList(NewList(Number("1.5"), True{}), Pair{Number("2"), False{}}).Scan(&scanned)
+	if err != nil || !reflect.DeepEqual(scanned, map[float64]bool{1.5: true, 2: false}) {
+		t.Errorf("Expected a map got %v, %v", scanned, err)
+	}
+
+	duplicated := NewList(Pair{Identifier("a"), Number("1")}, Pair{Identifier("a"), Number("2")})
+	var last map[string]int
+	err = Unmarshal(duplicated, &last)
+	if err != nil || last["a"] != 2 {
+		t.Errorf("Expected the last value got %v, %v", last, err)
+	}
+	existing := map[string]int{"a": 0}
+	err = DecodeOptions{Duplicates: DuplicateKeyError}.Unmarshal(NewList(Pair{Identifier("a"), Number("1")}), &existing)
+	if err != nil || existing["a"] != 1 {
+		t.Errorf("Expected existing keys to be replaced got %v, %v", existing, err)
+	}
+	err = DecodeOptions{Duplicates: DuplicateKeyError}.Unmarshal(duplicated, &last)
+	if err == nil {
+		t.Errorf("Expected an error for a duplicate key")
+	}
+
+	err = Unmarshal(NewList(Identifier("a")), &last)
+	if err == nil {
+		t.Errorf("Expected an error for an entry which isn't a pair")
+	}
+}
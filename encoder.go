@@ -1,33 +1,420 @@
 package s
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
+	"unicode/utf8"
 )
 
 type (
 	Encoder interface {
 		EncodeS() (Expression, error)
 	}
+	// EncodeOptions customises how values are encoded.
+	EncodeOptions struct {
+		// Runes encodes int32 values, which include runes, as Char instead
+		// of Number.
+		Runes bool
+		// Vectors encodes arrays as Vector, or ByteVector for arrays of
+		// bytes, so they can be told apart from slices.
+		Vectors bool
+		// SortMapKeys encodes map entries in key order instead of Go's
+		// random map order.
+		SortMapKeys bool
+		// Deterministic makes the output depend only on the value encoded:
+		// map entries are ordered by the bytes of their encoded keys, then
+		// of their values for keys which encode the same, and numbers are
+		// normalised, so 1.50 is written as 1.5 and -0 as 0. Unlike
+		// WriteCanonical it produces ordinary expressions.
+		Deterministic bool
+	}
+	// encodeSink receives the expressions produced by encodeValue
+	encodeSink interface {
+		expression(Expression) error
+		beginList() error
+		// beginVector starts a list which is ended by endList
+		beginVector() error
+		endList() error
+	}
+	// treeSink builds the expressions in memory
+	treeSink struct {
+		stack   [][]Expression
+		vectors []bool
+	}
+	// normalSink normalises the numbers of the expressions it passes on
+	normalSink struct {
+		encodeSink
+	}
+	// writerSink writes the expressions as they are produced
+	writerSink struct {
+		dst      io.Writer
+		separate bool
+	}
 )
 
-func encodeValue(src reflect.Value) (exp Expression, err error) {
-	var ok bool
+func (this *treeSink) expression(exp Expression) error {
+	top := len(this.stack) - 1
+	this.stack[top] = append(this.stack[top], exp)
+	return nil
+}
+func (this *treeSink) beginList() error {
+	this.stack = append(this.stack, []Expression{})
+	this.vectors = append(this.vectors, false)
+	return nil
+}
+func (this *treeSink) beginVector() error {
+	this.beginList()
+	this.vectors[len(this.vectors)-1] = true
+	return nil
+}
+func (this *treeSink) endList() error {
+	top := len(this.stack) - 1
+	var exp Expression = List(this.stack[top])
+	if this.vectors[len(this.vectors)-1] {
+		exp = Vector(this.stack[top])
+	}
+	this.stack = this.stack[:top]
+	this.vectors = this.vectors[:len(this.vectors)-1]
+	return this.expression(exp)
+}
+
+func (this normalSink) expression(exp Expression) error {
+	return this.encodeSink.expression(normalExpression(exp))
+}
+
+// normalNumber returns n in its normal form: exact values as integers,
+// terminating decimals or ratios, and infinities and NaN by name.
+func normalNumber(n Number) Number {
+	v, err := parseNumber(string(n))
+	// saturated values are kept as written rather than lose their value
+	if err != nil || v.saturated {
+		return n
+	}
+	if v.rat == nil {
+		return formatFloat(v.float)
+	}
+	return Number(ratString(v.rat))
+}
+
+// normalExpression returns exp with every number in normal form.
+func normalExpression(exp Expression) Expression {
+	switch t := exp.(type) {
+	case Number:
+		return normalNumber(t)
+	case List:
+		lst := make(List, len(t))
+		for i, e := range t {
+			lst[i] = normalExpression(e)
+		}
+		return lst
+	case Vector:
+		vector := make(Vector, len(t))
+		for i, e := range t {
+			vector[i] = normalExpression(e)
+		}
+		return vector
+	case Pair:
+		return Pair{normalExpression(t.Car), normalExpression(t.Cdr)}
+	}
+	return exp
+}
+
+func (this *writerSink) space() (err error) {
+	if this.separate {
+		_, err = io.WriteString(this.dst, " ")
+	}
+	return
+}
+func (this *writerSink) expression(exp Expression) error {
+	err := this.space()
+	if err != nil {
+		return err
+	}
+	this.separate = true
+	return exp.Write(this.dst)
+}
+func (this *writerSink) open(str string) error {
+	err := this.space()
+	if err != nil {
+		return err
+	}
+	this.separate = false
+	_, err = io.WriteString(this.dst, str)
+	return err
+}
+func (this *writerSink) beginList() error {
+	return this.open("(")
+}
+func (this *writerSink) beginVector() error {
+	return this.open("#(")
+}
+func (this *writerSink) endList() error {
+	this.separate = true
+	_, err := io.WriteString(this.dst, ")")
+	return err
+}
+
+// encodeBig encodes the arbitrary precision numbers of math/big exactly.
+func encodeBig(src reflect.Value) (exp Expression, ok bool, err error) {
+	if src.Kind() != reflect.Ptr && src.CanAddr() {
+		src = src.Addr()
+	}
+	if src.Kind() == reflect.Ptr && src.IsNil() {
+		return
+	}
+	v := src.Interface()
+	switch t := v.(type) {
+	case big.Int:
+		v = &t
+	case big.Float:
+		v = &t
+	case big.Rat:
+		v = &t
+	}
+
+	ok = true
+	switch t := v.(type) {
+	case *big.Int:
+		exp = Number(t.String())
+	case *big.Float:
+		if t.IsInf() {
+			exp = formatFloat(math.Inf(t.Sign()))
+		} else {
+			exp = Number(t.Text('f', -1))
+		}
+	case *big.Rat:
+		exp = Number(ratString(t))
+	default:
+		ok = false
+	}
+	return
+}
+
+// ratString returns r as a decimal when it has an exact one and as a
+// fraction otherwise.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// the decimal terminates when the denominator is made of 2s and 5s
+	den := new(big.Int).Set(r.Denom())
+	digits := 0
+	two, five, rem := big.NewInt(2), big.NewInt(5), new(big.Int)
+	for twos, fives := 0, 0; ; {
+		if rem.Mod(den, two).Sign() == 0 {
+			den.Quo(den, two)
+			twos++
+		} else if rem.Mod(den, five).Sign() == 0 {
+			den.Quo(den, five)
+			fives++
+		} else {
+			digits = twos
+			if fives > digits {
+				digits = fives
+			}
+			break
+		}
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return r.RatString()
+	}
+	return r.FloatString(digits)
+}
+
+// encodedKeys encodes map keys and sorts them by their encoded bytes.
+// The values are kept in step with their keys.
+func (this EncodeOptions) encodedKeys(keys, values []reflect.Value) ([]Expression, error) {
+	type entry struct {
+		key, value reflect.Value
+		exp        Expression
+		encoded    string
+		typ        string
+		// the encoded value, only needed to break ties
+		tie string
+	}
+	entries := make([]entry, len(keys))
+	for i, k := range keys {
+		exp, err := this.encode(k)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := normalString(exp)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry{key: k, value: values[i], exp: exp, encoded: encoded, typ: keyType(k)}
+	}
+	same := func(a, b entry) bool {
+		// keys of different types can encode the same way
+		return a.encoded == b.encoded && a.typ == b.typ
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.encoded != b.encoded {
+			return a.encoded < b.encoded
+		}
+		return a.typ < b.typ
+	})
+	// keys which still tie, such as NaNs, are ordered by their values
+	for i := 0; i < len(entries); {
+		j := i + 1
+		for j < len(entries) && same(entries[i], entries[j]) {
+			j++
+		}
+		if run := entries[i:j]; len(run) > 1 {
+			for k := range run {
+				exp, err := this.encode(run[k].value)
+				if err == nil {
+					run[k].tie, err = normalString(exp)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			sort.Slice(run, func(a, b int) bool {
+				return run[a].tie < run[b].tie
+			})
+		}
+		i = j
+	}
+	exps := make([]Expression, len(entries))
+	for i, e := range entries {
+		keys[i], values[i], exps[i] = e.key, e.value, e.exp
+	}
+	return exps, nil
+}
+
+// normalString returns the text of exp with its numbers normalised.
+func normalString(exp Expression) (string, error) {
+	var buf bytes.Buffer
+	err := normalExpression(exp).Write(&buf)
+	return buf.String(), err
+}
 
+func keyType(k reflect.Value) string {
+	if k.Kind() == reflect.Interface && !k.IsNil() {
+		k = k.Elem()
+	}
+	return k.Type().String()
+}
+
+// sortKeys orders map keys by type, then numbers, strings and booleans by
+// value and anything else, such as pointers and structs, by the bytes of
+// its encoding.
+func (this EncodeOptions) sortKeys(keys, values []reflect.Value) error {
+	type entry struct {
+		key, value reflect.Value
+		typ        string
+		encoded    string
+		tie        string
+	}
+	entries := make([]entry, len(keys))
+	for i, k := range keys {
+		if k.Kind() == reflect.Interface && !k.IsNil() {
+			k = k.Elem()
+		}
+		entries[i] = entry{key: keys[i], value: values[i], typ: keyType(k)}
+		if _, ordered := lessValue(k, k); ordered {
+			continue
+		}
+		exp, err := this.encode(k)
+		if err != nil {
+			return err
+		}
+		entries[i].encoded = fmt.Sprint(exp)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.typ != b.typ {
+			return a.typ < b.typ
+		}
+		if less, ordered := lessValue(a.key, b.key); ordered {
+			return less
+		}
+		return a.encoded < b.encoded
+	})
+	// keys which still tie, such as NaNs or pointers to equal values, are
+	// ordered by the encoding of their values
+	tied := func(a, b entry) bool {
+		if a.typ != b.typ || a.encoded != b.encoded {
+			return false
+		}
+		less, ordered := lessValue(a.key, b.key)
+		greater, _ := lessValue(b.key, a.key)
+		return !ordered || !less && !greater
+	}
+	for i := 0; i < len(entries); {
+		j := i + 1
+		for j < len(entries) && tied(entries[i], entries[j]) {
+			j++
+		}
+		if run := entries[i:j]; len(run) > 1 {
+			for k := range run {
+				exp, err := this.encode(run[k].value)
+				if err != nil {
+					return err
+				}
+				run[k].tie = fmt.Sprint(exp)
+			}
+			sort.Slice(run, func(a, b int) bool {
+				return run[a].tie < run[b].tie
+			})
+		}
+		i = j
+	}
+	for i, e := range entries {
+		keys[i], values[i] = e.key, e.value
+	}
+	return nil
+}
+
+// lessValue compares a and b of the same type when it's a number, string
+// or boolean, reporting whether it could.
+func lessValue(a, b reflect.Value) (less, ordered bool) {
+	if a.Kind() == reflect.Interface && !a.IsNil() && !b.IsNil() {
+		a, b = a.Elem(), b.Elem()
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return !a.Bool() && b.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		// NaN sorts first so the order stays strict
+		x, y := a.Float(), b.Float()
+		return x < y || math.IsNaN(x) && !math.IsNaN(y), true
+	case reflect.String:
+		return a.String() < b.String(), true
+	}
+	return false, false
+}
+
+func (this EncodeOptions) encodeValue(sink encodeSink, src reflect.Value) (err error) {
 	if src.CanInterface() {
-		exp, ok = src.Interface().(Expression)
+		exp, ok := src.Interface().(Expression)
 		if ok {
-			err = nil
-			return
+			return sink.expression(exp)
 		}
 
-		err, ok = src.Interface().(error)
+		e, ok := src.Interface().(error)
 		if ok {
-			exp = NewList(Identifier("error"), String(err.Error()))
-			err = nil
-			return
+			return sink.expression(NewList(Identifier("error"), String(e.Error())))
+		}
+
+		exp, ok, err = encodeBig(src)
+		if ok || err != nil {
+			if err != nil {
+				return
+			}
+			return sink.expression(exp)
 		}
 
 		encoder, ok := src.Interface().(Encoder)
@@ -41,85 +428,112 @@ func encodeValue(src reflect.Value) (exp Expression, err error) {
 		}
 		if ok && (src.Kind() != reflect.Ptr || !src.IsNil()) {
 			exp, err = encoder.EncodeS()
-			return
+			if err != nil {
+				return
+			}
+			return sink.expression(exp)
 		}
 	}
 
 	switch src.Kind() {
 	case reflect.Bool:
 		if src.Bool() {
-			exp = True{}
+			err = sink.expression(True{})
 		} else {
-			exp = False{}
+			err = sink.expression(False{})
 		}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		exp = Number(strconv.FormatInt(src.Int(), 10))
+	case reflect.Int32:
+		if this.Runes {
+			if r := rune(src.Int()); !utf8.ValidRune(r) {
+				err = fmt.Errorf("Cannot encode invalid rune %v as a character", r)
+			} else {
+				err = sink.expression(Char(r))
+			}
+			break
+		}
+		err = sink.expression(Number(strconv.FormatInt(src.Int(), 10)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64:
+		err = sink.expression(Number(strconv.FormatInt(src.Int(), 10)))
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		exp = Number(strconv.FormatUint(src.Uint(), 10))
+		err = sink.expression(Number(strconv.FormatUint(src.Uint(), 10)))
 	case reflect.Float32, reflect.Float64:
-		f := src.Float()
-		if math.IsInf(f, 0) {
-			err = fmt.Errorf("Infinity is not supported")
-		} else if math.IsNaN(f) {
-			err = fmt.Errorf("NaN is not supported")
-		} else {
-			exp = Number(strconv.FormatFloat(f, 'f', -1, 64))
-		}
+		err = sink.expression(formatFloat(src.Float()))
 	case reflect.String:
-		exp = String(src.String())
+		err = sink.expression(String(src.String()))
 	case reflect.Struct:
-		var e Expression
-		exps := make([]Expression, src.NumField())
-		for i := 0; i < src.NumField(); i++ {
-			e, err = encodeValue(src.Field(i))
-			if err != nil {
-				return
-			}
-			exps[i] = e
-		}
-		exp = List(exps)
+		err = this.encodeStruct(sink, src)
 	case reflect.Map:
 		if src.IsNil() {
-			exp = NewList()
+			err = sink.expression(NewList())
 			break
 		}
-		exps := make([]Expression, src.Len())
-		for i, k := range src.MapKeys() {
-			var ke, ve Expression
-			ke, err = encodeValue(k)
-			if err != nil {
-				return
+		// NaN keys can't be looked up, so values are collected with them
+		var keys, values []reflect.Value
+		for iter := src.MapRange(); iter.Next(); {
+			keys = append(keys, iter.Key())
+			values = append(values, iter.Value())
+		}
+		var encoded []Expression
+		switch {
+		case this.Deterministic:
+			encoded, err = this.encodedKeys(keys, values)
+		case this.SortMapKeys:
+			err = this.sortKeys(keys, values)
+		}
+		if err == nil {
+			err = sink.beginList()
+		}
+		for i, k := range keys {
+			if err == nil {
+				err = sink.beginList()
 			}
-			ve, err = encodeValue(src.MapIndex(k))
-			if err != nil {
-				return
+			if err == nil && encoded != nil {
+				err = sink.expression(encoded[i])
+			} else if err == nil {
+				err = this.encodeValue(sink, k)
+			}
+			if err == nil {
+				err = this.encodeValue(sink, values[i])
 			}
-			exps[i] = NewList(ke, ve)
+			if err == nil {
+				err = sink.endList()
+			}
+		}
+		if err == nil {
+			err = sink.endList()
 		}
-		exp = List(exps)
 	case reflect.Slice:
 		if src.IsNil() {
-			exp = NewList()
+			err = sink.expression(NewList())
 			break
 		}
 		fallthrough
 	case reflect.Array:
-		exps := make([]Expression, src.Len())
-		for i := 0; i < src.Len(); i++ {
-			var ve Expression
-			ve, err = encodeValue(src.Index(i))
-			if err != nil {
-				return
+		if this.Vectors && src.Kind() == reflect.Array {
+			if src.Type().Elem().Kind() == reflect.Uint8 {
+				bs := make(ByteVector, src.Len())
+				for i := range bs {
+					bs[i] = byte(src.Index(i).Uint())
+				}
+				err = sink.expression(bs)
+				break
 			}
-			exps[i] = ve
+			err = sink.beginVector()
+		} else {
+			err = sink.beginList()
+		}
+		for i := 0; err == nil && i < src.Len(); i++ {
+			err = this.encodeValue(sink, src.Index(i))
+		}
+		if err == nil {
+			err = sink.endList()
 		}
-		exp = List(exps)
 	case reflect.Interface, reflect.Ptr:
 		if src.IsNil() {
-			exp = NewList()
+			err = sink.expression(NewList())
 			break
 		}
-		exp, err = encodeValue(src.Elem())
+		err = this.encodeValue(sink, src.Elem())
 	default:
 		err = fmt.Errorf("Unable to convert `%v` of type `%v` into s expression", src, src.Kind())
 	}
@@ -127,16 +541,37 @@ func encodeValue(src reflect.Value) (exp Expression, err error) {
 }
 
 func Encode(src interface{}) (Expression, error) {
-	return encodeValue(reflect.ValueOf(src))
+	return EncodeOptions{}.Encode(src)
+}
+
+// Encode is like the package level Encode, using these options.
+func (this EncodeOptions) Encode(src interface{}) (Expression, error) {
+	return this.encode(reflect.ValueOf(src))
+}
+
+// sink returns the sink encodeValue should use to produce output for dst.
+func (this EncodeOptions) sink(dst encodeSink) encodeSink {
+	if this.Deterministic {
+		return normalSink{dst}
+	}
+	return dst
+}
+func (this EncodeOptions) encode(src reflect.Value) (Expression, error) {
+	tree := &treeSink{stack: [][]Expression{nil}}
+	err := this.encodeValue(this.sink(tree), src)
+	if err != nil {
+		return nil, err
+	}
+	return tree.stack[0][0], nil
 }
 func EncodeList(src interface{}) (List, error) {
-	e, err := encodeValue(reflect.ValueOf(src))
+	e, err := Encode(src)
 	if err != nil {
 		return nil, err
 	}
 	lst, ok := e.(List)
 	if !ok {
-		return nil, fmt.Errorf("Unable to convert %T into List")
+		return nil, fmt.Errorf("Unable to convert %T into List", src)
 	}
 	return lst, nil
 }
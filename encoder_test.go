@@ -2,6 +2,10 @@ package s
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -22,6 +26,9 @@ func TestEncoder(t *testing.T) {
 		{int32(-1), "-1"},
 		{int64(-1), "-1"},
 		{float64(-1.1), "-1.1"},
+		{math.Inf(1), "+inf.0"},
+		{float32(math.Inf(-1)), "-inf.0"},
+		{math.NaN(), "+nan.0"},
 		{[4]int{1, 2, 3, 4}, "(1 2 3 4)"},
 		{[]float64{1, 2, 3, 4}, "(1 2 3 4)"},
 		{"test", `"test"`},
@@ -46,3 +53,169 @@ func TestEncoder(t *testing.T) {
 		}
 	}
 }
+
+func TestEncoderRunes(t *testing.T) {
+	exp, err := EncodeOptions{Runes: true}.Encode([]rune("a\n"))
+	if err != nil || fmt.Sprint(exp) != `(#\a #\newline)` {
+		t.Errorf("Expected characters got %v, %v", exp, err)
+	}
+	var rs []rune
+	err = Unmarshal(exp, &rs)
+	if err != nil || string(rs) != "a\n" {
+		t.Errorf("Expected runes got %q, %v", string(rs), err)
+	}
+
+	exp, err = Encode('a')
+	if err != nil || exp != Number("97") {
+		t.Errorf("Expected a number got %v, %v", exp, err)
+	}
+
+	for _, r := range []rune{-1, 0xD800, 0x110000} {
+		exp, err = EncodeOptions{Runes: true}.Encode(r)
+		if err == nil {
+			t.Errorf("Expected an error for invalid rune %v got %v", r, exp)
+		}
+	}
+}
+
+func TestEncoderVectors(t *testing.T) {
+	type sample struct {
+		Point [2]int
+		Hash  [3]byte
+		Tags  []string
+	}
+	value := sample{[2]int{1, 2}, [3]byte{1, 2, 3}, []string{"a"}}
+	exp, err := EncodeOptions{Vectors: true}.Encode(value)
+	expected := `(#(1 2) #u8(1 2 3) ("a"))`
+	if err != nil || fmt.Sprint(exp) != expected {
+		t.Errorf("Expected %v got %v, %v", expected, exp, err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetOptions(EncodeOptions{Vectors: true})
+	err = enc.Encode(value)
+	if err != nil || buf.String() != expected+"\n" {
+		t.Errorf("Expected %v got %v, %v", expected, buf.String(), err)
+	}
+
+	var decoded sample
+	err = Unmarshal(exp, &decoded)
+	if err != nil || !reflect.DeepEqual(decoded, value) {
+		t.Errorf("Expected %v got %v, %v", value, decoded, err)
+	}
+}
+
+var ptrKeys = []int{1, 2, 3, 1}
+
+func TestEncoderSortMapKeys(t *testing.T) {
+	type testCase struct {
+		Value  interface{}
+		Result string
+	}
+	testCases := []testCase{
+		{map[string]int{"b": 2, "a": 1, "c": 3}, `(("a" 1) ("b" 2) ("c" 3))`},
+		{map[int]bool{10: true, -1: false, 2: true}, `((-1 #f) (2 #t) (10 #t))`},
+		{map[interface{}]int{"x": 1, 2: 2, 1: 3}, `((1 3) (2 2) ("x" 1))`},
+		{map[interface{}]int{"2": 1, 2: 2, 10: 3, "10": 4, 1.5: 5}, `((1.5 5) (2 2) (10 3) ("10" 4) ("2" 1))`},
+		{map[*int]string{&ptrKeys[2]: "c", &ptrKeys[0]: "a", &ptrKeys[1]: "b"}, `((1 "a") (2 "b") (3 "c"))`},
+		{map[float64]int{math.NaN(): 1, 1: 2, math.Inf(-1): 3}, `((+nan.0 1) (-inf.0 3) (1 2))`},
+		// tied keys are ordered by their values
+		{map[float64]int{math.NaN(): 3, math.NaN(): 1, math.NaN(): 2}, `((+nan.0 1) (+nan.0 2) (+nan.0 3))`},
+		{map[*int]string{&ptrKeys[3]: "y", &ptrKeys[0]: "x"}, `((1 "x") (1 "y"))`},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 200; i++ {
+			exp, err := EncodeOptions{SortMapKeys: true}.Encode(tc.Value)
+			if err != nil || fmt.Sprint(exp) != tc.Result {
+				t.Errorf("Expected `%v` got `%v`, %v", tc.Result, exp, err)
+				break
+			}
+		}
+	}
+}
+
+func TestEncoderDeterministic(t *testing.T) {
+	type sample struct {
+		Weights map[float64][]interface{}
+		Nested  map[string]map[int]string
+		Exp     Expression
+	}
+	value := sample{
+		Weights: map[float64][]interface{}{math.Copysign(0, -1): {1.5, big.NewFloat(-0)}, 10: {big.NewRat(4, 2)}, 2.25: nil},
+		Nested:  map[string]map[int]string{"b": {2: "x", 10: "y"}, "a": nil},
+		Exp:     NewList(Number("1.50"), Vector{Number("#x10"), Pair{Number("-0.0"), Number("2/4")}}),
+	}
+	expected := `(((0 (1.5 0)) (10 (2)) (2.25 ())) (("a" ()) ("b" ((10 "y") (2 "x")))) (1.5 #(16 (0 . 0.5))))`
+	for i := 0; i < 10; i++ {
+		exp, err := EncodeOptions{Deterministic: true}.Encode(value)
+		if err != nil || fmt.Sprint(exp) != expected {
+			t.Fatalf("Expected `%v` got `%v`, %v", expected, exp, err)
+		}
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetOptions(EncodeOptions{Deterministic: true})
+		err = enc.Encode(value)
+		if err != nil || buf.String() != expected+"\n" {
+			t.Fatalf("Expected `%v` got `%v`, %v", expected, buf.String(), err)
+		}
+	}
+
+	// keys which encode the same are ordered by their values
+	nans := map[interface{}]string{math.NaN(): "b", math.NaN(): "c", math.NaN(): "a", float32(1): "d", 1.0: "e"}
+	expected = `((+nan.0 "a") (+nan.0 "b") (+nan.0 "c") (1 "d") (1 "e"))`
+	for i := 0; i < 10; i++ {
+		exp, err := EncodeOptions{Deterministic: true}.Encode(nans)
+		if err != nil || fmt.Sprint(exp) != expected {
+			t.Fatalf("Expected `%v` got `%v`, %v", expected, exp, err)
+		}
+	}
+}
+
+func TestEncoderBig(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-340282366920938463463374607431768211455", 10)
+	precise, _ := new(big.Float).SetPrec(200).SetString("12345678901234567890.125")
+	type testCase struct {
+		Value  interface{}
+		Result string
+	}
+	testCases := []testCase{
+		{huge, "-340282366920938463463374607431768211455"},
+		{*big.NewInt(42), "42"},
+		{precise, "12345678901234567890.125"},
+		{big.NewRat(5, 4), "1.25"},
+		{big.NewRat(-1, 3), "-1/3"},
+		{new(big.Float).SetInf(true), "-inf.0"},
+		{struct{ Amount *big.Int }{big.NewInt(7)}, "(7)"},
+	}
+	for _, tc := range testCases {
+		exp, err := Encode(tc.Value)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.Value)
+			continue
+		}
+		if fmt.Sprint(exp) != tc.Result {
+			t.Errorf("Expected `%v` got `%v` for %v", tc.Result, exp, tc.Value)
+		}
+	}
+
+	var amounts struct {
+		Int   big.Int
+		Float *big.Float
+		Rat   big.Rat
+	}
+	err := Unmarshal(NewList(Number(huge.String()), Number("0.5"), Number("1.25")), &amounts)
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	if amounts.Int.Cmp(huge) != 0 || amounts.Float.String() != "0.5" || amounts.Rat.Cmp(big.NewRat(5, 4)) != 0 {
+		t.Errorf("Expected %v 0.5 5/4 got %v", huge, amounts)
+	}
+
+	var v interface{}
+	err = Number("18446744073709551616").Scan(&v)
+	if i, ok := v.(*big.Int); err != nil || !ok || i.String() != "18446744073709551616" {
+		t.Errorf("Expected a big.Int got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,253 @@
+package s
+
+import (
+	"errors"
+	"io"
+)
+
+type (
+	// Handler receives the expressions of the input as events instead of
+	// a tree, so arbitrarily large input is read in memory bounded by its
+	// nesting. depth counts the lists enclosing the event. open is the text
+	// opening the list: (, #(, #u8( or a quote prefix such as ', in which
+	// case the list starts with the quote's identifier like Read expands it.
+	// Dot separates the elements of a dotted list from its tail, which is
+	// the next event at the same depth.
+	Handler interface {
+		StartList(depth int, open string) error
+		EndList(depth int) error
+		Atom(depth int, exp Expression) error
+		Dot(depth int) error
+	}
+	// EventReader reads the input as Handler events. The embedded Reader's
+	// settings apply, with comments skipped unless Comments is set.
+	// Limits.MaxListLength isn't enforced since lists aren't kept.
+	EventReader struct {
+		*Reader
+		tokenizer Tokenizer
+		// the lists, quotes and datum comments enclosing the next token,
+		// which are kept so Handle can carry on after an error, and whether
+		// those from hidden on are skipped
+		frames   []frame
+		hidden   int
+		skipping bool
+	}
+	// frame is a list, quote or datum comment waiting for its end
+	frame int
+)
+
+const (
+	// emptyFrame is a ( list without elements, which can't be dotted yet
+	emptyFrame frame = iota
+	listFrame
+	vectorFrame
+	// dotFrame is a dotted list waiting for its tail and tailFrame one
+	// waiting for its end
+	dotFrame
+	tailFrame
+	quoteFrame
+	commentFrame
+)
+
+// SkipList is returned by StartList to skip the list without any events
+// for its elements or its end.
+var SkipList = errors.New("skip this list")
+
+// Handle reads every expression in reader, passing its events to handler.
+func Handle(reader io.Reader, handler Handler) error {
+	events := NewEventReader(reader)
+	for {
+		err := events.Handle(handler)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func NewEventReader(reader io.Reader) *EventReader {
+	rdr := NewReader(reader)
+	return &EventReader{Reader: rdr, tokenizer: Tokenizer{Reader: rdr}}
+}
+
+// Handle reads the next expression, passing its events to handler. It
+// returns io.EOF at the end of the input. Comments are passed as atoms when
+// Comments is set, except for datum comments which are always skipped.
+// After an error from handler, the next call carries on with the rest of
+// the expression.
+func (this *EventReader) Handle(handler Handler) error {
+	// datum comments are read as tokens so their datum is skipped without
+	// keeping it
+	comments := this.Comments
+	this.Comments = true
+	defer func() {
+		this.Comments = comments
+	}()
+
+	visible := func(i int) bool {
+		return !this.skipping || i < this.hidden
+	}
+	start := func(open string) error {
+		i := len(this.frames)
+		if !visible(i) {
+			return nil
+		}
+		err := handler.StartList(i, open)
+		if err == SkipList {
+			this.skipping, this.hidden, err = true, i, nil
+		}
+		return err
+	}
+
+	for {
+		tok, err := this.tokenizer.Next()
+		if err != nil {
+			return err
+		}
+		n := len(this.frames)
+		if err = this.checkDepth(tok); err != nil {
+			return err
+		}
+		if n > 0 && this.frames[n-1] == tailFrame {
+			// only comments may follow the tail of a dotted list
+			switch tok.Kind {
+			case TokenCloseList, TokenComment, TokenDatumComment, TokenDot:
+			default:
+				return this.syntaxError(tok.Start, "", "Expected ) after dotted tail", nil)
+			}
+		}
+
+		switch tok.Kind {
+		case TokenEOF:
+			if n > 0 {
+				return this.syntaxError(this.pos, "", "Missing datum", io.EOF)
+			}
+			return io.EOF
+		case TokenOpenList:
+			err = start(tok.Text)
+			if tok.Text == "(" {
+				this.frames = append(this.frames, emptyFrame)
+			} else {
+				this.frames = append(this.frames, vectorFrame)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		case TokenQuote:
+			err = start(tok.Text)
+			if err == nil && visible(n) {
+				err = handler.Atom(n+1, Identifier(abbreviations[tok.Text]))
+			}
+			this.frames = append(this.frames, quoteFrame)
+			if err != nil {
+				return err
+			}
+			continue
+		case TokenDatumComment:
+			if !this.skipping {
+				this.skipping, this.hidden = true, n
+			}
+			this.frames = append(this.frames, commentFrame)
+			continue
+		case TokenDot:
+			if n == 0 || this.frames[n-1] != listFrame {
+				return this.syntaxError(tok.Start, ".", "Unexpected dot", nil)
+			}
+			this.frames[n-1] = dotFrame
+			if visible(n) {
+				err = handler.Dot(n)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		case TokenCloseList:
+			switch this.frames[n-1] {
+			case quoteFrame, commentFrame:
+				return this.syntaxError(tok.Start, ")", "Missing datum", nil)
+			case dotFrame:
+				return this.syntaxError(tok.Start, ")", "Missing dotted tail", nil)
+			}
+			if visible(n - 1) {
+				err = handler.EndList(n - 1)
+			}
+			this.frames = this.frames[:n-1]
+		case TokenAtom:
+			if visible(n) {
+				err = handler.Atom(n, tok.Value)
+			}
+		case TokenComment:
+			if comments && visible(n) {
+				err = handler.Atom(n, tok.Value)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the datum just read completes any quotes and datum comments
+		// waiting for it, with the events stopping at the first error
+		this.show()
+		complete := true
+		for len(this.frames) > 0 {
+			i := len(this.frames) - 1
+			f := this.frames[i]
+			if f == emptyFrame || f == dotFrame {
+				this.frames[i]++
+			}
+			if f != quoteFrame && f != commentFrame {
+				break
+			}
+			if f == quoteFrame && visible(i) && err == nil {
+				err = handler.EndList(i)
+			}
+			this.frames = this.frames[:i]
+			this.show()
+			if f == commentFrame {
+				complete = false
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if complete && len(this.frames) == 0 {
+			return nil
+		}
+	}
+}
+
+// checkDepth fails once tok is nested deeper than MaxDepth. Like the Reader
+// it counts lists and quotes, and datum comments only until their datum
+// starts, so a datum is as deep as its comment but chained comments nest.
+func (this *EventReader) checkDepth(tok Token) error {
+	max := this.Limits.MaxDepth
+	if max <= 0 || tok.Kind == TokenCloseList || tok.Kind == TokenEOF {
+		return nil
+	}
+	depth := 0
+	for i, f := range this.frames {
+		waiting := tok.Kind == TokenDatumComment
+		if i+1 < len(this.frames) {
+			waiting = this.frames[i+1] == commentFrame
+		}
+		if f != commentFrame || waiting {
+			depth++
+		}
+	}
+	if depth > max {
+		return &LimitError{Position: tok.Start, Limit: int64(max), Err: ErrMaxDepth}
+	}
+	return nil
+}
+
+// show stops skipping once the skipped frames have ended.
+func (this *EventReader) show() {
+	if this.skipping && this.hidden >= len(this.frames) {
+		this.skipping = false
+	}
+}
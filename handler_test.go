@@ -0,0 +1,192 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordingHandler struct {
+	events []string
+	skip   string
+	// fail is returned once by the Atom event for this expression
+	fail Expression
+}
+
+func (this *recordingHandler) StartList(depth int, open string) error {
+	this.events = append(this.events, fmt.Sprintf("%v%v", depth, open))
+	if open == this.skip {
+		return SkipList
+	}
+	return nil
+}
+func (this *recordingHandler) EndList(depth int) error {
+	this.events = append(this.events, fmt.Sprintf("%v)", depth))
+	return nil
+}
+func (this *recordingHandler) Atom(depth int, exp Expression) error {
+	this.events = append(this.events, fmt.Sprintf("%v:%v", depth, exp))
+	if this.fail != nil && exp == this.fail {
+		this.fail = nil
+		return errors.New("failed")
+	}
+	return nil
+}
+func (this *recordingHandler) Dot(depth int) error {
+	this.events = append(this.events, fmt.Sprintf("%v.", depth))
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	type testCase struct {
+		input    string
+		skip     string
+		expected string
+	}
+	testCases := []testCase{
+		{`a "b"`, "", `0:a 0:"b"`},
+		{`(a (b) #(c))`, "", `0( 1:a 1( 2:b 1) 1#( 2:c 1) 0)`},
+		{`'(a 'b)`, "", `0' 1:quote 1( 2:a 2' 3:quote 3:b 2) 1) 0)`},
+		{`(a #;(b c) d) #;e f`, "", `0( 1:a 1:d 0) 0:f`},
+		{`(a #(b (c)) d) e`, "#(", `0( 1:a 1#( 1:d 0) 0:e`},
+		{`(a '(b) c)`, "'", `0( 1:a 1' 1:c 0)`},
+		{"(a ; comment\n b)", "", `0( 1:a 1:b 0)`},
+		{`(a . b) (a b . (c))`, "", `0( 1:a 1. 1:b 0) 0( 1:a 1:b 1. 1( 2:c 1) 0)`},
+		{`(a . #;b 'c #;d)`, "", `0( 1:a 1. 1' 2:quote 2:c 1) 0)`},
+		{`(#(a b) . c)`, "#(", `0( 1#( 1. 1:c 0)`},
+	}
+	for _, tc := range testCases {
+		handler := &recordingHandler{skip: tc.skip}
+		err := Handle(bytes.NewBufferString(tc.input), handler)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %q", err, tc.input)
+			continue
+		}
+		if got := strings.Join(handler.events, " "); got != tc.expected {
+			t.Errorf("Expected %v got %v for %q", tc.expected, got, tc.input)
+		}
+	}
+
+	invalid := map[string]string{
+		"(. b)":     "Unexpected dot",
+		"(a . b c)": "Expected ) after dotted tail",
+		"(a . b .)": "Unexpected dot",
+		"(a .)":     "Missing dotted tail",
+		"#(a . b)":  "Unexpected dot",
+		"(a ')":     "Missing datum",
+		"(a":        "Unterminated list",
+		"'":         "Missing datum",
+	}
+	for input, msg := range invalid {
+		err := Handle(bytes.NewBufferString(input), &recordingHandler{})
+		if serr, ok := err.(*SyntaxError); !ok || serr.Msg != msg {
+			t.Errorf("Expected %v got %v for %q", msg, err, input)
+		}
+	}
+}
+
+func TestEventReaderResumes(t *testing.T) {
+	handler := &recordingHandler{fail: Identifier("a")}
+	events := NewEventReader(bytes.NewBufferString(`(a 'b) c`))
+	var errs []error
+	for i := 0; i < 4; i++ {
+		errs = append(errs, events.Handle(handler))
+	}
+	if fmt.Sprint(errs) != "[failed <nil> <nil> EOF]" {
+		t.Errorf("Expected [failed <nil> <nil> EOF] got %v", errs)
+	}
+	expected := `0( 1:a 1' 2:quote 2:b 1) 0) 0:c`
+	if got := strings.Join(handler.events, " "); got != expected {
+		t.Errorf("Expected %v got %v", expected, got)
+	}
+}
+
+func TestEventReaderComments(t *testing.T) {
+	handler := &recordingHandler{}
+	events := NewEventReader(bytes.NewBufferString("; a\n#;(b c) d"))
+	// datum comments are skipped as tokens even without Comments, so
+	// MaxListLength isn't checked for them like it would be for a list read
+	events.Limits = Limits{MaxListLength: 1}
+	if err := events.Handle(handler); err != nil || strings.Join(handler.events, " ") != "0:d" {
+		t.Errorf("Expected 0:d got %v, %v", handler.events, err)
+	}
+	if events.Comments {
+		t.Errorf("Expected Comments to stay cleared")
+	}
+
+	handler = &recordingHandler{}
+	events = NewEventReader(bytes.NewBufferString("; a\nb"))
+	events.Comments = true
+	expected := []string{fmt.Sprintf("0:%v", Comment(" a")), "0:b"}
+	if err := events.Handle(handler); err != nil || !reflect.DeepEqual(handler.events, expected) {
+		t.Errorf("Expected %q got %q, %v", expected, handler.events, err)
+	}
+}
+
+func TestEventReaderLimits(t *testing.T) {
+	type testCase struct {
+		limits Limits
+		input  string
+		err    error
+	}
+	testCases := []testCase{
+		{Limits{MaxDepth: 2}, "((a) #(b) 'c)", nil},
+		{Limits{MaxDepth: 2}, "(((a)))", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "(a '''b)", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "''b", nil},
+		{Limits{MaxDepth: 2}, "'''b", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "#;#;#;#;a b c d e", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "#;#;#;a b c d", nil},
+		{Limits{MaxDepth: 1}, "(#;a b)", nil},
+		{Limits{MaxDepth: 1}, "(#;(a) b)", ErrMaxDepth},
+		{Limits{MaxDepth: 1}, "#;'a b", nil},
+		{Limits{MaxDepth: 1000}, strings.Repeat("'", 2000) + "a", ErrMaxDepth},
+	}
+	for _, tc := range testCases {
+		// the limits match the Reader's
+		rdr := NewReader(bytes.NewBufferString(tc.input))
+		rdr.Limits = tc.limits
+		_, readErr := Read(rdr)
+
+		events := NewEventReader(bytes.NewBufferString(tc.input))
+		events.Limits = tc.limits
+		err := events.Handle(&countingHandler{})
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) || !errors.Is(readErr, tc.err) || (readErr == nil) != (tc.err == nil) {
+			t.Errorf("Expected %v got %v and %v from Read for %.20q", tc.err, err, readErr, tc.input)
+		}
+	}
+}
+
+type countingHandler struct {
+	atoms int
+}
+
+func (this *countingHandler) StartList(depth int, open string) error { return nil }
+func (this *countingHandler) EndList(depth int) error                { return nil }
+func (this *countingHandler) Dot(depth int) error                    { return nil }
+func (this *countingHandler) Atom(depth int, exp Expression) error {
+	this.atoms++
+	return nil
+}
+
+func TestHandleLargeList(t *testing.T) {
+	const n = 100000
+	r := io.MultiReader(
+		strings.NewReader("("),
+		strings.NewReader(strings.Repeat("(a 1) ", n)),
+		strings.NewReader(")"),
+	)
+	handler := &countingHandler{}
+	events := NewEventReader(r)
+	err := events.Handle(handler)
+	if err != nil || handler.atoms != 2*n {
+		t.Errorf("Expected %v atoms got %v, %v", 2*n, handler.atoms, err)
+	}
+	if err = events.Handle(handler); err != io.EOF {
+		t.Errorf("Expected EOF got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package s
+
+import (
+	"strings"
+)
+
+type (
+	// KeywordStyle selects which identifiers a Reader reads as keywords.
+	KeywordStyle int
+	// PropertyList is a view of the keyword arguments of a list such as
+	// (server :host "x" :port 80). Each key is followed by its value, other
+	// elements are skipped. Keys are matched by name whether they are
+	// Keyword, :name or name: identifiers.
+	PropertyList struct {
+		List List
+	}
+)
+
+const (
+	// NoKeywords reads :name and name: as identifiers
+	NoKeywords KeywordStyle = iota
+	// LeadingColon reads :name as a keyword
+	LeadingColon
+	// TrailingColon reads name: as a keyword
+	TrailingColon
+)
+
+// keyword returns id as a keyword when it is one in this style.
+func (this KeywordStyle) keyword(id Identifier) Expression {
+	str := string(id)
+	if len(str) < 2 {
+		return id
+	}
+	switch {
+	case this == LeadingColon && str[0] == ':':
+		return Keyword(str[1:])
+	case this == TrailingColon && str[len(str)-1] == ':':
+		return Keyword(str[:len(str)-1])
+	}
+	return id
+}
+
+// keyName returns the name of exp when it's a key of a property list.
+func keyName(exp Expression) (string, bool) {
+	switch t := exp.(type) {
+	case Keyword:
+		return string(t), true
+	case Identifier:
+		str := string(t)
+		if len(str) < 2 {
+			return "", false
+		}
+		if strings.HasPrefix(str, ":") {
+			return str[1:], true
+		}
+		if strings.HasSuffix(str, ":") {
+			return str[:len(str)-1], true
+		}
+	}
+	return "", false
+}
+
+// index returns the index of the value of key, or -1.
+func (this *PropertyList) index(key string) int {
+	for i := 0; i < len(this.List); i++ {
+		name, ok := keyName(this.List[i])
+		if !ok || i+1 == len(this.List) {
+			continue
+		}
+		if name == key {
+			return i + 1
+		}
+		i++
+	}
+	return -1
+}
+
+// Get returns the value of key.
+func (this *PropertyList) Get(key string) (Expression, bool) {
+	i := this.index(key)
+	if i < 0 {
+		return nil, false
+	}
+	return this.List[i], true
+}
+
+// Set replaces the value of key, or appends the key and value when it isn't
+// in the list yet.
+func (this *PropertyList) Set(key string, value Expression) {
+	i := this.index(key)
+	if i < 0 {
+		this.List = append(this.List, Keyword(key), value)
+		return
+	}
+	this.List[i] = value
+}
+
+// Keys returns the names of the keys in order.
+func (this *PropertyList) Keys() []string {
+	var keys []string
+	for i := 0; i < len(this.List)-1; i++ {
+		if name, ok := keyName(this.List[i]); ok {
+			keys = append(keys, name)
+			i++
+		}
+	}
+	return keys
+}
@@ -0,0 +1,131 @@
+package s
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReaderKeywords(t *testing.T) {
+	type testCase struct {
+		style    KeywordStyle
+		input    string
+		expected Expression
+	}
+	testCases := []testCase{
+		{NoKeywords, "(:a b:)", NewList(Identifier(":a"), Identifier("b:"))},
+		{LeadingColon, "(:a b: :)", NewList(Keyword("a"), Identifier("b:"), Identifier(":"))},
+		{TrailingColon, "(:a b: :)", NewList(Identifier(":a"), Keyword("b"), Identifier(":"))},
+	}
+	for _, tc := range testCases {
+		rdr := NewReader(bytes.NewBufferString(tc.input))
+		rdr.Keywords = tc.style
+		exp, err := Read(rdr)
+		if err != nil || !reflect.DeepEqual(exp, tc.expected) {
+			t.Errorf("Expected %v got %v, %v for %v", tc.expected, exp, err, tc.input)
+		}
+	}
+
+	var buf bytes.Buffer
+	Keyword("host").Write(&buf)
+	if buf.String() != ":host" {
+		t.Errorf("Expected :host got %v", buf.String())
+	}
+
+	for _, k := range []Keyword{"host", "a b", "1", "a|b", "λ"} {
+		buf.Reset()
+		k.Write(&buf)
+		rdr := NewReader(&buf)
+		rdr.Keywords = LeadingColon
+		exp, err := Read(rdr)
+		if err != nil || exp != k {
+			t.Errorf("Expected %#v got %#v, %v", k, exp, err)
+		}
+	}
+}
+
+func TestPropertyList(t *testing.T) {
+	plist := PropertyList{List: NewList(Identifier("server"), Keyword("host"), String("x"), Identifier("port:"), Number("80"))}
+	if keys := plist.Keys(); !reflect.DeepEqual(keys, []string{"host", "port"}) {
+		t.Errorf("Expected host and port got %v", keys)
+	}
+	if v, ok := plist.Get("port"); !ok || v != Number("80") {
+		t.Errorf("Expected 80 got %v", v)
+	}
+	if _, ok := plist.Get("server"); ok {
+		t.Errorf("Expected the head not to be a key")
+	}
+
+	plist.Set("host", String("y"))
+	plist.Set("tls", True{})
+	expected := `(server :host "y" port: 80 :tls #t)`
+	if plist.List.String() != expected {
+		t.Errorf("Expected %v got %v", expected, plist.List)
+	}
+}
+
+func TestUnmarshalKeywords(t *testing.T) {
+	type server struct {
+		Kind string
+		Host string
+		Port int
+	}
+	rdr := NewReader(bytes.NewBufferString(`(server :port 80 :host "x")`))
+	rdr.Keywords = LeadingColon
+	exp, err := Read(rdr)
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+
+	var s server
+	err = Unmarshal(exp, &s)
+	expected := server{"server", "x", 80}
+	if err != nil || s != expected {
+		t.Errorf("Expected %v got %v, %v", expected, s, err)
+	}
+
+	s = server{}
+	err = NewList(Keyword("host"), String("y")).Scan(&s)
+	if err != nil || s.Host != "y" {
+		t.Errorf("Expected y got %v, %v", s.Host, err)
+	}
+
+	err = NewList(Keyword("host"), String("y"), Keyword("port")).Scan(&s)
+	if err == nil {
+		t.Errorf("Expected an error for a missing value")
+	}
+	err = NewList(Keyword("host"), String("y"), Keyword("unknown"), Number("1")).Scan(&s)
+	if err == nil {
+		t.Errorf("Expected an error for an unknown keyword")
+	}
+
+	// keywords which aren't followed by the value of a field are values
+	type mode struct {
+		Mode Keyword
+		N    int
+	}
+	rdr = NewReader(bytes.NewBufferString(`(:fast 5)`))
+	rdr.Keywords = LeadingColon
+	exp, err = Read(rdr)
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	var m mode
+	err = Unmarshal(exp, &m)
+	if err != nil || m != (mode{"fast", 5}) {
+		t.Errorf("Expected {fast 5} got %v, %v", m, err)
+	}
+	var last struct {
+		N    int
+		Mode string
+	}
+	err = NewList(Number("1"), Keyword("fast")).Scan(&last)
+	if err != nil || last.N != 1 || last.Mode != "fast" {
+		t.Errorf("Expected {1 fast} got %v, %v", last, err)
+	}
+	m = mode{}
+	err = NewList(Keyword("n"), Number("2")).Scan(&m)
+	if err != nil || m != (mode{N: 2}) {
+		t.Errorf("Expected {\"\" 2} got %v, %v", m, err)
+	}
+}
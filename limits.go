@@ -0,0 +1,104 @@
+package s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+type (
+	// Limits bounds the resources a Reader uses on hostile input. A zero
+	// field is unlimited.
+	Limits struct {
+		// MaxDepth is how many lists, vectors, quotes and datum comments an
+		// expression may be nested in.
+		MaxDepth int
+		// MaxAtomSize is the largest identifier, number, string, binary or
+		// comment in bytes.
+		MaxAtomSize int
+		// MaxListLength is the most elements a list or vector may have.
+		MaxListLength int
+		// MaxTotalBytes is the most bytes read from the underlying reader.
+		// It is only enforced for readers created by NewReader.
+		MaxTotalBytes int64
+	}
+	// LimitError is returned when input exceeds one of the Limits. Err is
+	// one of ErrMaxDepth, ErrMaxAtomSize, ErrMaxListLength or
+	// ErrMaxTotalBytes.
+	LimitError struct {
+		Position
+		Limit int64
+		Err   error
+	}
+	// countingReader counts the bytes read from the source of a Reader
+	countingReader struct {
+		src    io.Reader
+		reader *Reader
+		n      int64
+	}
+)
+
+var (
+	ErrMaxDepth      = errors.New("maximum depth")
+	ErrMaxAtomSize   = errors.New("maximum atom size")
+	ErrMaxListLength = errors.New("maximum list length")
+	ErrMaxTotalBytes = errors.New("maximum total bytes")
+)
+
+func (this *LimitError) Error() string {
+	return fmt.Sprintf("%v of %v exceeded at %v", this.Err, this.Limit, this.Position)
+}
+func (this *LimitError) Unwrap() error {
+	return this.Err
+}
+
+func (this *countingReader) Read(p []byte) (int, error) {
+	if max := this.reader.Limits.MaxTotalBytes; max > 0 {
+		if this.n >= max {
+			// input exactly max bytes long is fine, only a byte past the
+			// limit fails
+			var probe [1]byte
+			n, err := this.src.Read(probe[:])
+			if n == 0 {
+				return 0, err
+			}
+			return 0, &LimitError{Position: this.reader.pos, Limit: max, Err: ErrMaxTotalBytes}
+		}
+		if int64(len(p)) > max-this.n {
+			p = p[:max-this.n]
+		}
+	}
+	n, err := this.src.Read(p)
+	this.n += int64(n)
+	return n, err
+}
+
+// checkAtom fails once the atom starting at start grows past MaxAtomSize.
+func (this *Reader) checkAtom(start Position, size int) error {
+	if max := this.Limits.MaxAtomSize; max > 0 && size > max {
+		return &LimitError{Position: start, Limit: int64(max), Err: ErrMaxAtomSize}
+	}
+	return nil
+}
+
+// checkList fails once the list starting at start grows past MaxListLength.
+func (this *Reader) checkList(start Position, length int) error {
+	if max := this.Limits.MaxListLength; max > 0 && length > max {
+		return &LimitError{Position: start, Limit: int64(max), Err: ErrMaxListLength}
+	}
+	return nil
+}
+
+// enter records reading a nested expression, failing once expressions are
+// nested deeper than MaxDepth. Every call must be followed by leave.
+func (this *Reader) enter() error {
+	this.depth++
+	// the top level expression isn't nested in anything
+	if max := this.Limits.MaxDepth; max > 0 && this.depth > max+1 {
+		return &LimitError{Position: this.pos, Limit: int64(max), Err: ErrMaxDepth}
+	}
+	return nil
+}
+func (this *Reader) leave() {
+	this.depth--
+}
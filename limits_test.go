@@ -0,0 +1,86 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReaderLimits(t *testing.T) {
+	type testCase struct {
+		limits Limits
+		input  string
+		err    error
+	}
+	deep := strings.Repeat("(", 100000) + strings.Repeat(")", 100000)
+	testCases := []testCase{
+		{Limits{MaxDepth: 2}, "((a) #(b) 'c)", nil},
+		{Limits{MaxDepth: 2}, "(((a)))", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "(a '''b)", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "#;#;#;#;a b c d e", ErrMaxDepth},
+		{Limits{MaxDepth: 2}, "#;#;#;a b c d", nil},
+		{Limits{MaxDepth: 1}, "(#;a b)", nil},
+		{Limits{MaxDepth: 1}, "(#;(a) b)", ErrMaxDepth},
+		{Limits{MaxDepth: 1000}, deep, ErrMaxDepth},
+		{Limits{MaxAtomSize: 3}, `(abc "def" 123 #bYWJj)`, nil},
+		{Limits{MaxAtomSize: 3}, `abcd`, ErrMaxAtomSize},
+		{Limits{MaxAtomSize: 3}, `"abcd"`, ErrMaxAtomSize},
+		{Limits{MaxAtomSize: 3}, `1234`, ErrMaxAtomSize},
+		{Limits{MaxAtomSize: 3}, `#bYWJjZA==`, ErrMaxAtomSize},
+		{Limits{MaxAtomSize: 3}, "; long comment\na", ErrMaxAtomSize},
+		{Limits{MaxAtomSize: 3}, `|abcd|`, ErrMaxAtomSize},
+		{Limits{MaxListLength: 2}, "(a (b c) #(d e))", ErrMaxListLength},
+		{Limits{MaxListLength: 2}, "((a b) #(c d))", nil},
+		{Limits{MaxListLength: 2}, "#(a b c)", ErrMaxListLength},
+		{Limits{MaxTotalBytes: 9}, "(a b c d)", nil},
+		{Limits{MaxTotalBytes: 8}, "(a b c d)", ErrMaxTotalBytes},
+		{Limits{MaxTotalBytes: 3}, "abc", nil},
+		{Limits{MaxTotalBytes: 3}, "123", nil},
+		{Limits{MaxTotalBytes: 3}, "abcd", ErrMaxTotalBytes},
+		{Limits{MaxTotalBytes: 3}, "ab ", nil},
+	}
+	for _, tc := range testCases {
+		rdr := NewReader(bytes.NewBufferString(tc.input))
+		rdr.Limits = tc.limits
+		_, err := Read(rdr)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("Expected %v got %v for %.20q", tc.err, err, tc.input)
+			continue
+		}
+		var lerr *LimitError
+		if err != nil && !errors.As(err, &lerr) {
+			t.Errorf("Expected a LimitError got %T for %.20q", err, tc.input)
+		}
+	}
+}
+
+func TestCanonicalLimits(t *testing.T) {
+	rdr := NewReader(bytes.NewBufferString("(1:a(3:abc))"))
+	rdr.Limits = Limits{MaxAtomSize: 2}
+	_, err := rdr.readCanonical()
+	if !errors.Is(err, ErrMaxAtomSize) {
+		t.Errorf("Expected %v got %v", ErrMaxAtomSize, err)
+	}
+
+	rdr = NewReader(bytes.NewBufferString("(999999999:"))
+	rdr.Limits = Limits{MaxAtomSize: 1 << 20}
+	_, err = rdr.readCanonical()
+	if !errors.Is(err, ErrMaxAtomSize) {
+		t.Errorf("Expected %v got %v", ErrMaxAtomSize, err)
+	}
+}
+
+func TestStreamDecoderLimits(t *testing.T) {
+	dec := NewDecoder(bytes.NewBufferString("(1 2) (1 2 3)"))
+	dec.SetLimits(Limits{MaxListLength: 2})
+	var xs []int
+	err := dec.DecodeInto(&xs)
+	if err != nil || len(xs) != 2 {
+		t.Errorf("Expected 2 numbers got %v, %v", xs, err)
+	}
+	err = dec.DecodeInto(&xs)
+	if !errors.Is(err, ErrMaxListLength) {
+		t.Errorf("Expected %v got %v", ErrMaxListLength, err)
+	}
+}
@@ -0,0 +1,308 @@
+package s
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type (
+	// NumberMode controls how numbers which don't fit their destination
+	// are scanned.
+	NumberMode int
+	// NumberError is returned when a number can't be converted exactly in
+	// NumberStrict mode, or isn't a valid number at all.
+	NumberError struct {
+		Number Number
+		Type   reflect.Type
+		Err    error
+	}
+)
+
+const (
+	// NumberSaturate clamps out of range values to the nearest value the
+	// destination can hold and truncates fractions towards zero.
+	NumberSaturate NumberMode = iota
+	// NumberStrict returns a NumberError instead.
+	NumberStrict
+)
+
+var (
+	ErrNumberSyntax   = errors.New("invalid syntax")
+	ErrNumberRange    = errors.New("value out of range")
+	ErrNumberFraction = errors.New("fractional part would be lost")
+	ErrNumberSign     = errors.New("negative value for an unsigned type")
+)
+
+func (this *NumberError) Error() string {
+	return fmt.Sprintf("Cannot convert number %v to %v: %v", this.Number, this.Type, this.Err)
+}
+func (this *NumberError) Unwrap() error {
+	return this.Err
+}
+
+// numberValue is the value of a Number. Infinities and NaN have no exact
+// value so only their float is kept, as do decimals whose exponent is too
+// large to expand, which are saturated to an infinity or zero.
+type numberValue struct {
+	rat       *big.Rat
+	float     float64
+	inexact   bool
+	saturated bool
+}
+
+// maxExponent bounds the exponent of a decimal so expanding it stays cheap.
+const maxExponent = 10000
+
+var radixes = map[byte]int{'b': 2, 'o': 8, 'd': 10, 'x': 16}
+
+// parseDigits parses a non-empty run of digits in the given radix.
+func parseDigits(str string, radix int) (*big.Int, bool) {
+	if str == "" {
+		return nil, false
+	}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		var d int
+		switch {
+		case '0' <= c && c <= '9':
+			d = int(c - '0')
+		case 'a' <= c && c <= 'z':
+			d = int(c-'a') + 10
+		case 'A' <= c && c <= 'Z':
+			d = int(c-'A') + 10
+		default:
+			return nil, false
+		}
+		if d >= radix {
+			return nil, false
+		}
+	}
+	return new(big.Int).SetString(str, radix)
+}
+
+// parseDecimal parses digits with an optional period and exponent, such as
+// 1.5, .5, 1. or 15e-1.
+func parseDecimal(str string) (numberValue, error) {
+	exponent := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(str[i+1:])
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			e = maxExponent + 1
+			if str[i+1] == '-' {
+				e = -e
+			}
+		} else if err != nil {
+			return numberValue{}, ErrNumberSyntax
+		}
+		exponent, str = e, str[:i]
+	}
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		exponent -= len(str) - i - 1
+		str = str[:i] + str[i+1:]
+	}
+	mantissa, ok := parseDigits(str, 10)
+	if !ok {
+		return numberValue{}, ErrNumberSyntax
+	}
+	v := numberValue{rat: new(big.Rat), inexact: true}
+	if mantissa.Sign() == 0 {
+		return v, nil
+	}
+	// no float can hold these, so they saturate without being expanded
+	if exponent > maxExponent {
+		return numberValue{float: math.Inf(1), inexact: true, saturated: true}, nil
+	}
+	if exponent < -maxExponent {
+		return numberValue{inexact: true, saturated: true}, nil
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent < 0 {
+		v.rat.SetFrac(mantissa, scale)
+	} else {
+		v.rat.SetInt(mantissa.Mul(mantissa, scale))
+	}
+	return v, nil
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// parseNumber returns the value of a number written in Scheme's syntax: an
+// optional #b, #o, #d or #x radix prefix and sign followed by an integer,
+// a ratio such as 1/3 or a decimal such as 1.5e10, or one of +inf.0, -inf.0
+// and +nan.0. It returns ErrNumberSyntax for malformed numbers.
+func parseNumber(str string) (numberValue, error) {
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return numberValue{rat: new(big.Rat).SetInt64(i)}, nil
+	}
+
+	radix := 10
+	if len(str) > 2 && str[0] == '#' {
+		r, ok := radixes[str[1]|0x20]
+		if !ok {
+			return numberValue{}, ErrNumberSyntax
+		}
+		radix, str = r, str[2:]
+	}
+	negative := false
+	if len(str) > 0 && (str[0] == '+' || str[0] == '-') {
+		negative = str[0] == '-'
+		switch strings.ToLower(str[1:]) {
+		case "inf.0":
+			sign := 1
+			if negative {
+				sign = -1
+			}
+			return numberValue{float: math.Inf(sign), inexact: true}, nil
+		case "nan.0":
+			return numberValue{float: math.NaN(), inexact: true}, nil
+		}
+		str = str[1:]
+	}
+
+	var v numberValue
+	if i := strings.IndexByte(str, '/'); i >= 0 {
+		num, ok := parseDigits(str[:i], radix)
+		den, ok2 := parseDigits(str[i+1:], radix)
+		if !ok || !ok2 || den.Sign() == 0 {
+			return numberValue{}, ErrNumberSyntax
+		}
+		v.rat = new(big.Rat).SetFrac(num, den)
+	} else if radix == 10 && strings.ContainsAny(str, ".eE") {
+		var err error
+		v, err = parseDecimal(str)
+		if err != nil {
+			return numberValue{}, err
+		}
+	} else {
+		i, ok := parseDigits(str, radix)
+		if !ok {
+			return numberValue{}, ErrNumberSyntax
+		}
+		v.rat = new(big.Rat).SetInt(i)
+	}
+	if negative {
+		if v.rat == nil {
+			v.float = -v.float
+		} else {
+			v.rat.Neg(v.rat)
+		}
+	}
+	return v, nil
+}
+
+// isNumeric reports whether an atom starting with a digit, sign or period
+// is read as a number, or rejected as a malformed one, rather than as an
+// identifier such as - or ...
+func isNumeric(str string) bool {
+	if _, err := parseNumber(str); err != ErrNumberSyntax {
+		return true
+	}
+	if strings.HasPrefix(str, "+") || strings.HasPrefix(str, "-") {
+		str = str[1:]
+	}
+	str = strings.TrimPrefix(str, ".")
+	return len(str) > 0 && isDigit(rune(str[0]))
+}
+
+// integer converts v to an integer of the given range. A nil bound is
+// unlimited.
+func integer(v numberValue, min, max *big.Int, mode NumberMode) (*big.Int, error) {
+	if v.rat == nil {
+		switch {
+		case mode == NumberStrict || math.IsNaN(v.float):
+			return nil, ErrNumberRange
+		case v.float == 0:
+			return new(big.Int), nil
+		case v.float < 0 && min != nil:
+			return min, nil
+		case v.float > 0 && max != nil:
+			return max, nil
+		}
+		return nil, ErrNumberRange
+	}
+
+	r := v.rat
+	i := new(big.Int).Quo(r.Num(), r.Denom())
+	if !r.IsInt() && mode == NumberStrict {
+		return nil, ErrNumberFraction
+	}
+	if min != nil && i.Cmp(min) < 0 {
+		if mode == NumberStrict {
+			if min.Sign() == 0 {
+				return nil, ErrNumberSign
+			}
+			return nil, ErrNumberRange
+		}
+		return min, nil
+	}
+	if max != nil && i.Cmp(max) > 0 {
+		if mode == NumberStrict {
+			return nil, ErrNumberRange
+		}
+		return max, nil
+	}
+	return i, nil
+}
+
+func signedInteger(v numberValue, bits uint, mode NumberMode) (int64, error) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits-1), big.NewInt(1))
+	min := new(big.Int).Sub(new(big.Int).Neg(max), big.NewInt(1))
+	i, err := integer(v, min, max, mode)
+	if err != nil {
+		return 0, err
+	}
+	return i.Int64(), nil
+}
+
+func unsignedInteger(v numberValue, bits uint, mode NumberMode) (uint64, error) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits), big.NewInt(1))
+	i, err := integer(v, new(big.Int), max, mode)
+	if err != nil {
+		return 0, err
+	}
+	return i.Uint64(), nil
+}
+
+func float(v numberValue, bits int, mode NumberMode) (float64, error) {
+	if v.saturated && mode == NumberStrict {
+		return 0, ErrNumberRange
+	}
+	if v.rat == nil {
+		return v.float, nil
+	}
+	var f float64
+	if bits == 32 {
+		f32, _ := v.rat.Float32()
+		f = float64(f32)
+	} else {
+		f, _ = v.rat.Float64()
+	}
+	if math.IsInf(f, 0) && mode == NumberStrict {
+		return 0, ErrNumberRange
+	}
+	return f, nil
+}
+
+// formatFloat writes f as a number, using Scheme's names for infinities
+// and NaN.
+func formatFloat(f float64) Number {
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf.0"
+	case math.IsInf(f, -1):
+		return "-inf.0"
+	case math.IsNaN(f):
+		return "+nan.0"
+	}
+	return Number(strconv.FormatFloat(f, 'f', -1, 64))
+}
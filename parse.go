@@ -0,0 +1,213 @@
+package s
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+type (
+	// ParseOptions configures ParseBytes and ParseString like the fields
+	// of a Reader. Limits.MaxTotalBytes isn't needed as the input is
+	// already in memory. Numbers are checked against a NumberMode when
+	// they are decoded, with DecodeOptions.
+	ParseOptions struct {
+		Spans    bool
+		Comments bool
+		Keywords KeywordStyle
+		Limits   Limits
+		Advanced bool
+	}
+	// atomText collects the text of an atom. Reading from memory it only
+	// tracks where the text lies in the input, which the atom then shares
+	// or copies alone, until an escape makes the text differ from the
+	// input.
+	atomText struct {
+		reader   *Reader
+		buf      bytes.Buffer
+		from, to int
+		copied   bool
+	}
+)
+
+// ParseBytes reads the first expression in data, returning it along with
+// the number of bytes consumed. The input is scanned directly rather than
+// through a bufio.Reader, and only the text of each atom is copied.
+func ParseBytes(data []byte) (Expression, int, error) {
+	exp, _, n, err := ParseOptions{}.ParseBytes(data)
+	return exp, n, err
+}
+
+// ParseString reads the first expression in str, returning it along with
+// the number of bytes consumed. Identifiers, numbers, comments and strings
+// without escapes share the memory of str.
+func ParseString(str string) (Expression, int, error) {
+	exp, _, n, err := ParseOptions{}.ParseString(str)
+	return exp, n, err
+}
+
+// ParseBytes is like the package level ParseBytes, using these options. It
+// also returns the span of the expression when Spans is set.
+func (this ParseOptions) ParseBytes(data []byte) (Expression, *Span, int, error) {
+	rdr := this.reader()
+	// a nil input is told apart from an empty string by memory
+	rdr.data = data
+	return rdr.parse()
+}
+
+// ParseString is like the package level ParseString, using these options.
+// It also returns the span of the expression when Spans is set.
+func (this ParseOptions) ParseString(str string) (Expression, *Span, int, error) {
+	rdr := this.reader()
+	rdr.input = str
+	return rdr.parse()
+}
+
+func (this ParseOptions) reader() *Reader {
+	return &Reader{
+		Spans:    this.Spans,
+		Comments: this.Comments,
+		Keywords: this.Keywords,
+		Limits:   this.Limits,
+		Advanced: this.Advanced,
+		pos:      Position{Line: 1, Column: 1},
+		memory:   true,
+	}
+}
+
+func (this *Reader) parse() (Expression, *Span, int, error) {
+	exp, err := this.readExpression()
+	if err != nil {
+		return nil, nil, this.pos.Offset, err
+	}
+	return exp, this.span, this.pos.Offset, nil
+}
+
+// size returns the length of the input of a memory reader.
+func (this *Reader) size() int {
+	if this.data != nil {
+		return len(this.data)
+	}
+	return len(this.input)
+}
+
+// at returns the byte at offset i in the input of a memory reader.
+func (this *Reader) at(i int) byte {
+	if this.data != nil {
+		return this.data[i]
+	}
+	return this.input[i]
+}
+
+// text returns the input of a memory reader from offset from to to,
+// sharing it when the input is a string.
+func (this *Reader) text(from, to int) string {
+	if this.data != nil {
+		return string(this.data[from:to])
+	}
+	return this.input[from:to]
+}
+
+// peekByte returns the byte i bytes ahead without reading it.
+func (this *Reader) peekByte(i int) (byte, error) {
+	if !this.memory {
+		bs, err := this.Peek(i + 1)
+		if len(bs) > i {
+			return bs[i], nil
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	if off := this.pos.Offset + i; off < this.size() {
+		return this.at(off), nil
+	}
+	return 0, io.EOF
+}
+
+func (this *Reader) nextRune() (rune, int, error) {
+	if !this.memory {
+		return this.ReadRune()
+	}
+	off := this.pos.Offset
+	if off >= this.size() {
+		return 0, 0, io.EOF
+	}
+	if b := this.at(off); b < utf8.RuneSelf {
+		return rune(b), 1, nil
+	}
+	var r rune
+	var size int
+	if this.data != nil {
+		r, size = utf8.DecodeRune(this.data[off:])
+	} else {
+		r, size = utf8.DecodeRuneInString(this.input[off:])
+	}
+	return r, size, nil
+}
+func (this *Reader) nextByte() (byte, error) {
+	if !this.memory {
+		return this.ReadByte()
+	}
+	if this.pos.Offset >= this.size() {
+		return 0, io.EOF
+	}
+	return this.at(this.pos.Offset), nil
+}
+
+// newAtomText starts the text of an atom at offset from in the input.
+func (this *Reader) newAtomText(from int) atomText {
+	return atomText{reader: this, from: from, to: from}
+}
+
+// WriteByte adds the byte just read to the text.
+func (this *atomText) WriteByte(b byte) error {
+	if this.reader.memory && !this.copied {
+		this.to = this.reader.pos.Offset
+		return nil
+	}
+	return this.buf.WriteByte(b)
+}
+
+// WriteRune adds the rune just read to the text.
+func (this *atomText) WriteRune(r rune) {
+	if this.reader.memory && !this.copied {
+		this.to = this.reader.pos.Offset
+		return
+	}
+	this.buf.WriteRune(r)
+}
+
+// WriteString adds the string just read to the text.
+func (this *atomText) WriteString(s string) {
+	if this.reader.memory && !this.copied {
+		this.to = this.reader.pos.Offset
+		return
+	}
+	this.buf.WriteString(s)
+}
+
+// escape prepares for text which differs from the input, copying what has
+// been kept so far.
+func (this *atomText) escape() *bytes.Buffer {
+	if this.reader.memory && !this.copied {
+		this.buf.WriteString(this.reader.text(this.from, this.to))
+		this.copied = true
+	}
+	return &this.buf
+}
+
+func (this *atomText) Len() int {
+	if this.reader.memory && !this.copied {
+		return this.to - this.from
+	}
+	return this.buf.Len()
+}
+
+func (this *atomText) String() string {
+	if this.reader.memory && !this.copied {
+		return this.reader.text(this.from, this.to)
+	}
+	return this.buf.String()
+}
@@ -0,0 +1,141 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseString(t *testing.T) {
+	inputs := []string{
+		`abc`,
+		`  (a "b" 1.5 #\x) rest`,
+		`"a\tb\x41;" x`,
+		`|a\|b| c`,
+		`(a . b)`,
+		`'(1 #(2 3) #u8(4))`,
+		"; comment\n (a #| block |# #;b c)",
+		`#bYWJj)`,
+		`-1/2 `,
+		`((a`,
+		`"abc`,
+		`(1-2)`,
+		`   `,
+	}
+	for _, input := range inputs {
+		rdr := NewReader(strings.NewReader(input))
+		expected, expectedErr := Read(rdr)
+		for _, parse := range []func(string) (Expression, int, error){
+			ParseString,
+			func(str string) (Expression, int, error) { return ParseBytes([]byte(str)) },
+		} {
+			exp, n, err := parse(input)
+			if !reflect.DeepEqual(err, expectedErr) || !reflect.DeepEqual(exp, expected) {
+				t.Errorf("Expected %#v, %v got %#v, %v for %q", expected, expectedErr, exp, err, input)
+			}
+			if err == nil && n != rdr.pos.Offset {
+				t.Errorf("Expected %v bytes consumed got %v for %q", rdr.pos.Offset, n, input)
+			}
+		}
+	}
+}
+
+func TestParseBytesSequence(t *testing.T) {
+	data := []byte(`(a 1) "b" c `)
+	var exps []Expression
+	for {
+		exp, n, err := ParseBytes(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected no error got %v", err)
+		}
+		exps = append(exps, exp)
+		data = data[n:]
+	}
+	expected := []Expression{NewList(Identifier("a"), Number("1")), String("b"), Identifier("c")}
+	if !reflect.DeepEqual(exps, expected) {
+		t.Errorf("Expected %v got %v", expected, exps)
+	}
+}
+
+func TestParseBytesCopiesAtoms(t *testing.T) {
+	data := append([]byte("a "), bytes.Repeat([]byte("x"), 1<<20)...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	exp, n, err := ParseBytes(data)
+	runtime.ReadMemStats(&after)
+	if exp != Identifier("a") || n != 1 || err != nil {
+		t.Fatalf("Expected a, 1 got %#v, %v, %v", exp, n, err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<16 {
+		t.Errorf("Expected only the atom to be copied got %v bytes allocated", allocated)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	options := ParseOptions{Comments: true, Keywords: LeadingColon}
+	exp, _, _, err := options.ParseString("; a\n b")
+	if exp != Comment(" a") || err != nil {
+		t.Errorf("Expected a comment got %#v, %v", exp, err)
+	}
+	exp, _, _, err = options.ParseBytes([]byte(":b"))
+	if exp != Keyword("b") || err != nil {
+		t.Errorf("Expected a keyword got %#v, %v", exp, err)
+	}
+
+	options = ParseOptions{Limits: Limits{MaxDepth: 1}}
+	if _, _, _, err = options.ParseString("((a))"); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("Expected %v got %v", ErrMaxDepth, err)
+	}
+	if _, _, _, err = options.ParseBytes([]byte("(a)")); err != nil {
+		t.Errorf("Expected no error got %v", err)
+	}
+}
+
+func TestParseOptionsSpans(t *testing.T) {
+	input := " (a\n  \"b\")"
+	rdr := NewReader(strings.NewReader(input))
+	rdr.Spans = true
+	if _, err := Read(rdr); err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	options := ParseOptions{Spans: true}
+	_, span, _, err := options.ParseString(input)
+	if err != nil || !reflect.DeepEqual(span, rdr.Span()) {
+		t.Errorf("Expected %v got %v, %v", rdr.Span(), span, err)
+	}
+	_, span, _, err = options.ParseBytes([]byte(input))
+	if err != nil || !reflect.DeepEqual(span, rdr.Span()) {
+		t.Errorf("Expected %v got %v, %v", rdr.Span(), span, err)
+	}
+	if _, span, _, _ = (ParseOptions{}).ParseString(input); span != nil {
+		t.Errorf("Expected no span got %v", span)
+	}
+}
+
+func TestParseStringShares(t *testing.T) {
+	short, long := `("a" b)`, "(\""+strings.Repeat("a", 1000)+"\" "+strings.Repeat("b", 1000)+")"
+	allocs := func(input string) float64 {
+		return testing.AllocsPerRun(100, func() {
+			ParseString(input)
+		})
+	}
+	if s, l := allocs(short), allocs(long); s != l {
+		t.Errorf("Expected atoms to share the input got %v allocations for short atoms and %v for long", s, l)
+	}
+
+	exp, _, _ := ParseString(`"a\nb"`)
+	if exp != String("a\nb") {
+		t.Errorf("Expected an escaped string got %#v", exp)
+	}
+	exp, _, _ = ParseBytes(bytes.Repeat([]byte("x"), 3))
+	if exp != Identifier("xxx") {
+		t.Errorf("Expected xxx got %#v", exp)
+	}
+}
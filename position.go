@@ -0,0 +1,39 @@
+package s
+
+import (
+	"fmt"
+)
+
+type (
+	// Position is a location in the input. Offset counts bytes from zero,
+	// Line and Column count lines and characters from one.
+	Position struct {
+		Offset, Line, Column int
+	}
+	// Span is the source range of an expression. For lists Children holds
+	// the span of each element.
+	Span struct {
+		Start, End Position
+		Children   []*Span
+	}
+	SyntaxError struct {
+		Position
+		Token string
+		Msg   string
+		Err   error
+	}
+)
+
+func (this Position) String() string {
+	return fmt.Sprintf("line %v, column %v", this.Line, this.Column)
+}
+
+func (this *SyntaxError) Error() string {
+	if this.Token == "" {
+		return fmt.Sprintf("%v at %v", this.Msg, this.Position)
+	}
+	return fmt.Sprintf("%v `%v` at %v", this.Msg, this.Token, this.Position)
+}
+func (this *SyntaxError) Unwrap() error {
+	return this.Err
+}
@@ -0,0 +1,159 @@
+package s
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// Printer writes expressions across multiple lines. Lists, vectors and
+	// dotted lists which fit in MaxWidth are kept on one line, others are
+	// broken with one element per line.
+	Printer struct {
+		// Indent is the number of spaces the elements of a broken list are
+		// indented by relative to its opening parenthesis. Defaults to 2.
+		Indent int
+		// MaxWidth is the line width lists are kept within when possible.
+		// Defaults to 80.
+		MaxWidth int
+		// Rules customises the layout of lists by their head identifier.
+		Rules map[string]Rule
+	}
+	// Rule is the layout of a list with a particular head.
+	Rule struct {
+		// Inline is the number of elements after the head kept on the head's
+		// line when the list is broken.
+		Inline int
+		// Break lays the list out across lines even when it would fit.
+		Break bool
+	}
+	printer struct {
+		*Printer
+		dst    io.Writer
+		column int
+		err    error
+	}
+)
+
+func (this *printer) write(str string) {
+	if this.err != nil {
+		return
+	}
+	_, this.err = io.WriteString(this.dst, str)
+	if i := strings.LastIndexByte(str, '\n'); i >= 0 {
+		this.column = utf8.RuneCountInString(str[i+1:])
+	} else {
+		this.column += utf8.RuneCountInString(str)
+	}
+}
+func (this *printer) newline(column int) {
+	this.write("\n" + strings.Repeat(" ", column))
+}
+
+// elements returns the opening of a list, vector or dotted list with its
+// elements and any dotted tail.
+func elements(exp Expression) (open string, elems []Expression, tail Expression, ok bool) {
+	switch t := exp.(type) {
+	case List:
+		return "(", t, nil, len(t) > 0
+	case Vector:
+		return "#(", t, nil, len(t) > 0
+	case Pair:
+		for pair, ok := exp.(Pair); ok; pair, ok = exp.(Pair) {
+			elems = append(elems, pair.Car)
+			exp = pair.Cdr
+		}
+		// (a . (b c)) is the same as (a b c)
+		if lst, ok := exp.(List); ok {
+			return "(", append(elems, lst...), nil, true
+		}
+		return "(", elems, exp, true
+	}
+	return "", nil, nil, false
+}
+
+func (this *printer) print(exp Expression) {
+	var buf bytes.Buffer
+	this.err = exp.Write(&buf)
+	if this.err != nil {
+		return
+	}
+	flat := buf.String()
+
+	open, elems, tail, ok := elements(exp)
+	if !ok {
+		this.write(strings.TrimSuffix(flat, "\n"))
+		return
+	}
+
+	if lst, ok := exp.(List); ok {
+		if prefix, ok := lst.abbreviation(); ok {
+			this.write(prefix)
+			this.print(lst[1])
+			return
+		}
+	}
+
+	var rule Rule
+	head, isIdentifier := elems[0].(Identifier)
+	isIdentifier = isIdentifier && open == "("
+	if isIdentifier {
+		rule = this.Rules[string(head)]
+	}
+	if !rule.Break &&
+		!strings.Contains(flat, "\n") &&
+		this.column+utf8.RuneCountInString(flat) <= this.MaxWidth {
+		this.write(flat)
+		return
+	}
+
+	start := this.column
+	this.write(open)
+	children := elems
+	indent := start + len(open)
+	if isIdentifier {
+		this.print(head)
+		children = elems[1:]
+		indent = start + this.Indent
+		for i := 0; i < rule.Inline && len(children) > 0; i++ {
+			if _, ok := children[0].(Comment); ok {
+				break
+			}
+			this.write(" ")
+			this.print(children[0])
+			children = children[1:]
+		}
+	} else {
+		this.print(children[0])
+		children = children[1:]
+	}
+	for _, child := range children {
+		this.newline(indent)
+		this.print(child)
+	}
+	if tail != nil {
+		this.newline(indent)
+		this.write(". ")
+		this.print(tail)
+	} else if _, ok := elems[len(elems)-1].(Comment); ok {
+		// a line comment runs to the end of the line
+		this.newline(indent)
+	}
+	this.write(")")
+}
+
+// Print writes exp to dst.
+func (this *Printer) Print(dst io.Writer, exp Expression) error {
+	settings := *this
+	if settings.Indent <= 0 {
+		settings.Indent = 2
+	}
+	if settings.MaxWidth <= 0 {
+		settings.MaxWidth = 80
+	}
+	p := &printer{Printer: &settings, dst: dst}
+	p.print(exp)
+	return p.err
+}
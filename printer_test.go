@@ -0,0 +1,56 @@
+package s
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	define := NewList(
+		Identifier("define"), Identifier("config"),
+		NewList(
+			NewList(Identifier("host"), String("example.com")),
+			NewList(Identifier("port"), Number("8080")),
+		),
+	)
+	type testCase struct {
+		printer Printer
+		exp     Expression
+		result  string
+	}
+	testCases := []testCase{
+		{Printer{}, Number("1"), `1`},
+		{Printer{}, define, `(define config ((host "example.com") (port 8080)))`},
+		{Printer{MaxWidth: 40}, define, `(define
+  config
+  ((host "example.com") (port 8080)))`},
+		{Printer{MaxWidth: 30}, define, `(define
+  config
+  ((host "example.com")
+   (port 8080)))`},
+		{Printer{Indent: 4, Rules: map[string]Rule{"define": {Inline: 1, Break: true}}}, define, `(define config
+    ((host "example.com") (port 8080)))`},
+		{Printer{}, NewList(Identifier("a"), Comment(" note"), Identifier("b"), Comment(" end")), `(a
+  ; note
+  b
+  ; end
+  )`},
+		{Printer{MaxWidth: 40}, Vector{String("alpha beta gamma"), NewList(Identifier("delta"), String("epsilon zeta"))}, `#("alpha beta gamma"
+  (delta "epsilon zeta"))`},
+		{Printer{MaxWidth: 40}, Cons(Identifier("host"), Cons(String("example.com"), String("a long dotted tail"))), `(host
+  "example.com"
+  . "a long dotted tail")`},
+		{Printer{MaxWidth: 20}, NewList(Identifier("a"), Cons(Number("1"), Vector{Number("2"), Number("3")})), `(a (1 . #(2 3)))`},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		err := tc.printer.Print(&buf, tc.exp)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.exp)
+			continue
+		}
+		if buf.String() != tc.result {
+			t.Errorf("Expected\n%v\ngot\n%v", tc.result, buf.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package s
+
+import (
+	"fmt"
+)
+
+// Quasiquote fills a template, replacing (unquote name) with the binding
+// for name and splicing the elements of the list bound to name in place of
+// (unquote-splicing name). A template read from `x is unwrapped first.
+// Nested quasiquotes are left for a later expansion.
+func Quasiquote(template Expression, bindings map[string]Expression) (Expression, error) {
+	if lst, ok := template.(List); ok {
+		if prefix, ok := lst.abbreviation(); ok && prefix == "`" {
+			template = lst[1]
+		}
+	}
+	return quasiquote(template, bindings, 1)
+}
+
+func unquote(exp Expression, bindings map[string]Expression) (Expression, error) {
+	name, ok := exp.(Identifier)
+	if !ok {
+		return nil, fmt.Errorf("Expected an identifier to unquote got %v", exp)
+	}
+	value, ok := bindings[string(name)]
+	if !ok {
+		return nil, fmt.Errorf("Unbound identifier %v", name)
+	}
+	return value, nil
+}
+
+func quasiquote(exp Expression, bindings map[string]Expression, depth int) (Expression, error) {
+	if pair, ok := exp.(Pair); ok {
+		car, err := quasiquote(pair.Car, bindings, depth)
+		if err != nil {
+			return nil, err
+		}
+		cdr, err := quasiquote(pair.Cdr, bindings, depth)
+		if err != nil {
+			return nil, err
+		}
+		return Cons(car, cdr), nil
+	}
+
+	lst, ok := exp.(List)
+	if !ok {
+		return exp, nil
+	}
+
+	if prefix, ok := lst.abbreviation(); ok {
+		switch prefix {
+		case "`":
+			depth++
+		case ",":
+			if depth == 1 {
+				return unquote(lst[1], bindings)
+			}
+			depth--
+		case ",@":
+			if depth == 1 {
+				return nil, fmt.Errorf("Unquote-splicing outside of a list")
+			}
+			depth--
+		}
+		inner, err := quasiquote(lst[1], bindings, depth)
+		if err != nil {
+			return nil, err
+		}
+		return NewList(lst[0], inner), nil
+	}
+
+	// the reader turns `(a . ,b) into (a unquote b), whose tail is b
+	elems := lst
+	var tail Expression
+	dotted := false
+	if n := len(lst); depth == 1 && n > 2 && lst[n-2] == Identifier("unquote") {
+		value, err := unquote(lst[n-1], bindings)
+		if err != nil {
+			return nil, err
+		}
+		elems, tail, dotted = lst[:n-2], value, true
+	}
+
+	result := List{}
+	for _, elem := range elems {
+		if splice, ok := elem.(List); ok && depth == 1 {
+			if prefix, ok := splice.abbreviation(); ok && prefix == ",@" {
+				value, err := unquote(splice[1], bindings)
+				if err != nil {
+					return nil, err
+				}
+				values, ok := value.(List)
+				if !ok {
+					return nil, fmt.Errorf("Expected a list to splice got %v", value)
+				}
+				result = append(result, values...)
+				continue
+			}
+		}
+		value, err := quasiquote(elem, bindings, depth)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	if !dotted {
+		return result, nil
+	}
+	for i := len(result) - 1; i >= 0; i-- {
+		tail = Cons(result[i], tail)
+	}
+	return tail, nil
+}
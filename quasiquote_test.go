@@ -0,0 +1,73 @@
+package s
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestQuoteAbbreviations(t *testing.T) {
+	type testCase struct {
+		input  string
+		result Expression
+		output string
+	}
+	testCases := []testCase{
+		{`'x`, NewList(Identifier("quote"), Identifier("x")), `'x`},
+		{"`(a ,b ,@c)", NewList(Identifier("quasiquote"), NewList(
+			Identifier("a"),
+			NewList(Identifier("unquote"), Identifier("b")),
+			NewList(Identifier("unquote-splicing"), Identifier("c")),
+		)), "`(a ,b ,@c)"},
+		{`(quote x y)`, NewList(Identifier("quote"), Identifier("x"), Identifier("y")), `(quote x y)`},
+		{`''1`, NewList(Identifier("quote"), NewList(Identifier("quote"), Number("1"))), `''1`},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.input)
+			continue
+		}
+		if fmt.Sprint(exp) != fmt.Sprint(tc.result) || fmt.Sprint(exp) != tc.output {
+			t.Errorf("Expected %v got %v for %v", tc.output, exp, tc.input)
+		}
+	}
+}
+
+func TestQuasiquote(t *testing.T) {
+	bindings := map[string]Expression{
+		"name":  String("x"),
+		"ports": NewList(Number("80"), Number("443")),
+	}
+	type testCase struct {
+		template string
+		result   string
+	}
+	testCases := []testCase{
+		{"(server (name ,name) (ports ,@ports) (all ,ports))", `(server (name "x") (ports 80 443) (all (80 443)))`},
+		{"`(a ,name)", `(a "x")`},
+		{"(a `(b ,(c ,name)))", "(a `(b ,(c \"x\")))"},
+		{"`(a . ,name)", `(a . "x")`},
+		{"(a b . ,ports)", "(a b 80 443)"},
+		{"(a . ,missing)", ""},
+		{"(a ,missing)", ""},
+		{",@ports", ""},
+	}
+	for _, tc := range testCases {
+		template, err := Read(bytes.NewBufferString(tc.template))
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.template)
+			continue
+		}
+		exp, err := Quasiquote(template, bindings)
+		if tc.result == "" {
+			if err == nil {
+				t.Errorf("Expected an error got %v for %v", exp, tc.template)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(exp) != tc.result {
+			t.Errorf("Expected %v got %v, %v for %v", tc.result, exp, err, tc.template)
+		}
+	}
+}
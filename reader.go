@@ -7,17 +7,48 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type (
-	Reader  struct{ *bufio.Reader }
+	Reader struct {
+		*bufio.Reader
+		// Spans enables recording the source span of each expression read
+		Spans bool
+		// Comments keeps comments as Comment, BlockComment and DatumComment
+		// expressions instead of skipping them like whitespace
+		Comments bool
+		// Keywords selects whether :name or name: identifiers, quoted or
+		// not, are read as Keyword. Defaults to NoKeywords.
+		Keywords KeywordStyle
+		// Limits bounds the resources used reading hostile input
+		Limits Limits
+		// Advanced enables the atoms of Rivest's advanced syntax: 3:abc,
+		// #616263#, |YWJj|, [hint]atom and quoted strings with his escapes.
+		// |YWJj| then replaces the |quoted| symbols of the default syntax.
+		Advanced bool
+
+		pos, prev Position
+		span      *Span
+		depth     int
+		// memory readers scan data, or input when data is nil, in place
+		memory bool
+		data   []byte
+		input  string
+	}
 	nothing struct{}
 )
 
 var (
+	// Deprecated: identifiers and numbers are no longer cut off at a fixed
+	// length. Use Reader.Limits instead.
 	MAX_IDENTIFIER_LENGTH = 256
-	MAX_NUMBER_LENGTH     = 64
-	extended              map[rune]nothing
+	// Deprecated: use Reader.Limits instead.
+	MAX_NUMBER_LENGTH = 64
+	extended          map[rune]nothing
 )
 
 func init() {
@@ -34,131 +65,547 @@ func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 func isLetter(ch rune) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 func isExtended(ch rune) bool {
 	_, ok := extended[ch]
 	return ok
 }
 
-func (this Reader) skipWhitespace() error {
+func hexValue(b byte) (rune, bool) {
+	switch {
+	case '0' <= b && b <= '9':
+		return rune(b - '0'), true
+	case 'a' <= b && b <= 'f':
+		return rune(b-'a') + 10, true
+	case 'A' <= b && b <= 'F':
+		return rune(b-'A') + 10, true
+	}
+	return 0, false
+}
+
+func isDelimiter(ch rune) bool {
+	return isWhitespace(ch) || ch == '(' || ch == ')' || ch == '"' || ch == ';'
+}
+
+// begin starts the positions of a zero Reader, whose Line is 0, at line 1,
+// column 1 like those of NewReader.
+func (this *Reader) begin() {
+	if this.pos.Line == 0 {
+		this.pos.Line, this.pos.Column = 1, 1
+	}
+}
+
+func (this *Reader) readRune() (rune, error) {
+	r, size, err := this.nextRune()
+	if err != nil {
+		return r, err
+	}
+	this.prev = this.pos
+	this.pos.Offset += size
+	if r == '\n' {
+		this.pos.Line++
+		this.pos.Column = 1
+	} else {
+		this.pos.Column++
+	}
+	return r, nil
+}
+func (this *Reader) unreadRune() error {
+	var err error
+	if !this.memory {
+		err = this.UnreadRune()
+	}
+	if err == nil {
+		this.pos = this.prev
+	}
+	return err
+}
+func (this *Reader) readByte() (byte, error) {
+	b, err := this.nextByte()
+	if err != nil {
+		return b, err
+	}
+	this.prev = this.pos
+	this.pos.Offset++
+	if b == '\n' {
+		this.pos.Line++
+		this.pos.Column = 1
+	} else if b&0xC0 != 0x80 {
+		// continuation bytes belong to the previous character
+		this.pos.Column++
+	}
+	return b, nil
+}
+
+func (this *Reader) syntaxError(pos Position, token, msg string, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if _, ok := err.(*SyntaxError); ok {
+			return err
+		}
+		// errors from the underlying reader are returned as is
+		if _, ok := err.(base64.CorruptInputError); !ok {
+			return err
+		}
+	}
+	return &SyntaxError{Position: pos, Token: token, Msg: msg, Err: err}
+}
+
+// skipWhitespace skips whitespace and, unless they are kept, comments.
+func (this *Reader) skipWhitespace() error {
 	for {
-		r, _, err := this.ReadRune()
+		b, err := this.peekByte(0)
 		if err != nil {
 			return err
 		}
-		if !isWhitespace(r) {
-			return this.UnreadRune()
+		start := this.pos
+		switch {
+		case isWhitespace(rune(b)):
+			_, err = this.readByte()
+		case b == ';' && !this.Comments:
+			this.readByte()
+			_, err = this.readComment()
+		case b == '#' && !this.Comments:
+			next, perr := this.peekByte(1)
+			if perr != nil || (next != '|' && next != ';') {
+				return nil
+			}
+			this.readByte()
+			this.readByte()
+			if next == '|' {
+				_, err = this.readBlockComment(start)
+			} else {
+				_, err = this.readDatumComment(start)
+			}
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 	}
-	return nil
 }
 
-func (this Reader) readString() (String, error) {
-	var buf bytes.Buffer
-	var err error
-	var n int
-	tmp := make([]byte, 1)
-	escape := false
+func (this *Reader) readComment() (Comment, error) {
+	start := this.prev
+	buf := this.newAtomText(this.pos.Offset)
+	for {
+		b, err := this.readByte()
+		if err == io.EOF || b == '\n' {
+			// the \r of a \r\n line break isn't part of the comment
+			return Comment(strings.TrimSuffix(buf.String(), "\r")), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		buf.WriteByte(b)
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return "", err
+		}
+	}
+}
+func (this *Reader) readBlockComment(start Position) (BlockComment, error) {
+	buf := this.newAtomText(this.pos.Offset)
+	var prev byte
+	depth := 1
+	for {
+		b, err := this.readByte()
+		if err != nil {
+			return "", this.syntaxError(start, "#|", "Unterminated comment", err)
+		}
+		buf.WriteByte(b)
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return "", err
+		}
+		switch {
+		case prev == '|' && b == '#':
+			depth--
+			if depth == 0 {
+				text := buf.String()
+				return BlockComment(text[:len(text)-2]), nil
+			}
+			b = 0
+		case prev == '#' && b == '|':
+			depth++
+			b = 0
+		}
+		prev = b
+	}
+}
+func (this *Reader) readDatumComment(start Position) (DatumComment, error) {
+	var exp Expression
+	// datum comments waiting for their datum nest like lists, as in #;#;a b,
+	// but the datum is as deep as the comment
+	var tok Token
+	err := this.enter()
+	if err == nil {
+		tok, err = this.readToken()
+	}
+	this.leave()
+	switch {
+	case err != nil:
+	case tok.Kind == TokenEOF:
+		err = io.EOF
+	case tok.Kind == TokenCloseList:
+		// the list ends before the datum, as in (a #;)
+		return DatumComment{}, this.syntaxError(start, "#;", "Missing datum", nil)
+	default:
+		exp, err = this.readFrom(tok)
+	}
+	if err != nil {
+		return DatumComment{}, this.syntaxError(start, "#;", "Missing datum", err)
+	}
+	return DatumComment{exp}, nil
+}
+
+func (this *Reader) readString() (String, error) {
+	str, err := this.readDelimited('"', "string")
+	return String(str), err
+}
+
+// readSymbol reads a |quoted| symbol, which may hold any character.
+func (this *Reader) readSymbol() (Identifier, error) {
+	str, err := this.readDelimited('|', "symbol")
+	return Identifier(str), err
+}
+
+// readDelimited reads the text up to the closing quote, decoding escapes.
+// what names the text in errors.
+func (this *Reader) readDelimited(quote byte, what string) (string, error) {
+	start := this.prev
+	buf := this.newAtomText(this.pos.Offset)
+	unterminated := func(err error) error {
+		return this.syntaxError(start, string(quote), "Unterminated "+what, err)
+	}
 
-outer:
 	for {
-		n, err = this.Read(tmp)
+		b, err := this.readByte()
 		if err != nil {
-			break outer
+			return "", unterminated(err)
 		}
-		if n == 0 {
-			err = io.EOF
-			break outer
+
+		switch b {
+		case quote:
+			return buf.String(), nil
+		case '\\':
+			err = this.readEscape(buf.escape(), quote, unterminated)
+			if err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(b)
+		}
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return "", err
 		}
+	}
+}
 
-		if escape {
-			switch tmp[0] {
-			case 'n':
-				err = buf.WriteByte('\n')
-			case 'r':
-				err = buf.WriteByte('\r')
-			default:
-				err = buf.WriteByte(tmp[0])
+// readEscape reads the escape following a backslash in text delimited by
+// quote, with unterminated giving the error for input which ends early.
+// Besides the single character escapes it handles \xHH; with any number of
+// hex digits, \uXXXX including surrogate pairs, \UXXXXXXXX and line
+// continuations, which skip a line break and the spaces around it.
+func (this *Reader) readEscape(buf *bytes.Buffer, quote byte, unterminated func(error) error) error {
+	escape := this.prev
+	seq := []byte{'\\'}
+	next := func() (byte, error) {
+		b, err := this.readByte()
+		if err != nil {
+			return 0, unterminated(err)
+		}
+		seq = append(seq, b)
+		return b, nil
+	}
+	invalid := func() error {
+		return this.syntaxError(escape, string(seq), "Invalid escape", nil)
+	}
+	readHex := func(n int) (rune, error) {
+		var r rune
+		for i := 0; i < n; i++ {
+			b, err := next()
+			if err != nil {
+				return 0, err
 			}
-			escape = false
-		} else {
-			switch tmp[0] {
-			case '"':
-				break outer
-			case '\\':
-				escape = true
-			default:
-				err = buf.WriteByte(tmp[0])
+			d, ok := hexValue(b)
+			if !ok {
+				return 0, invalid()
 			}
+			r = r<<4 | d
 		}
+		return r, nil
+	}
 
+	b, err := next()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case '0':
+		buf.WriteByte(0)
+	case '\\', quote:
+		buf.WriteByte(b)
+	case 'x':
+		var r rune
+		for n := 0; ; n++ {
+			b, err = next()
+			if err != nil {
+				return err
+			}
+			if b == ';' && n > 0 {
+				break
+			}
+			d, ok := hexValue(b)
+			if !ok || n >= 8 {
+				return invalid()
+			}
+			r = r<<4 | d
+		}
+		if !utf8.ValidRune(r) {
+			return invalid()
+		}
+		buf.WriteRune(r)
+	case 'u':
+		r, err := readHex(4)
 		if err != nil {
-			break outer
+			return err
+		}
+		if utf16.IsSurrogate(r) {
+			// a high surrogate must be followed by an escaped low surrogate
+			if r >= 0xDC00 {
+				return invalid()
+			}
+			for _, c := range []byte{'\\', 'u'} {
+				b, err = next()
+				if err != nil {
+					return err
+				}
+				if b != c {
+					return invalid()
+				}
+			}
+			low, err := readHex(4)
+			if err != nil {
+				return err
+			}
+			r = utf16.DecodeRune(r, low)
+			if r == utf8.RuneError {
+				return invalid()
+			}
+		}
+		buf.WriteRune(r)
+	case 'U':
+		r, err := readHex(8)
+		if err != nil {
+			return err
+		}
+		if !utf8.ValidRune(r) {
+			return invalid()
+		}
+		buf.WriteRune(r)
+	case ' ', '\t', '\n', '\r':
+		for b == ' ' || b == '\t' {
+			b, err = next()
+			if err != nil {
+				return err
+			}
 		}
+		if b != '\n' && b != '\r' {
+			return invalid()
+		}
+		if c, err := this.peekByte(0); b == '\r' && err == nil && c == '\n' {
+			this.readByte()
+		}
+		for {
+			c, err := this.peekByte(0)
+			if err != nil || (c != ' ' && c != '\t') {
+				break
+			}
+			this.readByte()
+		}
+	default:
+		return invalid()
 	}
+	return nil
+}
 
-	return String(buf.String()), err
+// isPlainIdentifier reports whether str reads back as the same identifier
+// without being quoted as |str|.
+func isPlainIdentifier(str string) bool {
+	if str == "" || str == "." || isNumeric(str) {
+		return false
+	}
+	for i, r := range str {
+		if !(isLetter(r) || isExtended(r) || i > 0 && isDigit(r)) {
+			return false
+		}
+	}
+	return true
 }
-func (this Reader) readIdentifier(initial rune) (Identifier, error) {
+func (this *Reader) readIdentifier(initial rune) (Identifier, error) {
 	var r rune
 	var err error
 
-	buf := bytes.NewBuffer(make([]byte, 0, MAX_IDENTIFIER_LENGTH))
+	start := this.prev
+	buf := this.newAtomText(start.Offset)
 	buf.WriteRune(initial)
-	for buf.Len() < MAX_IDENTIFIER_LENGTH {
-		r, _, err = this.ReadRune()
+	for {
+		r, err = this.readRune()
+		if err != nil {
+			break
+		}
 		if !(isDigit(r) || isLetter(r) || isExtended(r)) {
-			this.UnreadRune()
+			this.unreadRune()
 			break
 		}
 		buf.WriteRune(r)
-		if err != nil {
-			break
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return "", err
 		}
 	}
 
 	return Identifier(buf.String()), err
 }
-func (this Reader) readList() (List, error) {
-	var err error
-	var lst List
-	var exp Expression
-	var b byte
+func (this *Reader) readList() (Expression, []*Span, error) {
+	var children []*Span
+	var tail Expression
+	start := this.prev
 	exps := []Expression{}
 
 	for {
-		// Skip opening whitespace
-		err = this.skipWhitespace()
+		tok, err := this.readToken()
 		if err != nil {
-			return lst, err
+			return nil, nil, err
 		}
-
-		b, err = this.ReadByte()
-		if err != nil {
-			return lst, err
+		if tok.Kind == TokenEOF {
+			return nil, nil, this.syntaxError(start, "(", "Unterminated list", io.EOF)
 		}
-		if b == ')' {
+		if tok.Kind == TokenCloseList {
 			break
 		}
-		err = this.UnreadByte()
-		if err != nil {
-			return lst, err
+		// a dot on its own separates the tail of a dotted list
+		if tok.Kind == TokenDot {
+			if len(exps) == 0 || tail != nil {
+				return nil, nil, this.syntaxError(tok.Start, ".", "Unexpected dot", nil)
+			}
+			tail, err = this.readTail()
+			if err != nil {
+				return nil, nil, err
+			}
+			if this.Spans {
+				children = append(children, this.span)
+			}
+			continue
 		}
 
-		exp, err = this.readExpression()
+		exp, err := this.readFrom(tok)
 		if err != nil {
-			return lst, err
+			return nil, nil, err
+		}
+		if tail != nil {
+			// only comments may follow the tail of a dotted list
+			if !isComment(exp) {
+				return nil, nil, this.syntaxError(tok.Start, "", "Expected ) after dotted tail", nil)
+			}
+			continue
 		}
 		exps = append(exps, exp)
+		if err = this.checkList(start, len(exps)); err != nil {
+			return nil, nil, err
+		}
+		if this.Spans {
+			children = append(children, this.span)
+		}
+	}
+
+	if tail == nil {
+		return List(exps), children, nil
+	}
+	for i := len(exps) - 1; i >= 0; i-- {
+		tail = Cons(exps[i], tail)
+	}
+	return tail, children, nil
+}
+
+// readVector reads the elements of #(...) after its opening parenthesis.
+func (this *Reader) readVector(start Position) (Vector, []*Span, error) {
+	exp, children, err := this.readList()
+	if err != nil {
+		return nil, nil, err
 	}
+	lst, ok := exp.(List)
+	if !ok {
+		return nil, nil, this.syntaxError(start, "#(", "Unexpected dot in vector", nil)
+	}
+	return Vector(lst), children, nil
+}
 
-	lst = List(exps)
-	return lst, nil
+// readByteVector reads the elements of #u8(...) after its opening
+// parenthesis.
+func (this *Reader) readByteVector(start Position) (ByteVector, []*Span, error) {
+	vector, children, err := this.readVector(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs := make(ByteVector, 0, len(vector))
+	for i, exp := range vector {
+		if isComment(exp) {
+			continue
+		}
+		n, ok := exp.(Number)
+		var b uint8
+		if !ok || n.ScanMode(&b, NumberStrict) != nil {
+			pos := start
+			if children != nil {
+				pos = children[i].Start
+			}
+			return nil, nil, this.syntaxError(pos, fmt.Sprint(exp), "Invalid byte", nil)
+		}
+		bs = append(bs, b)
+	}
+	return bs, children, nil
+}
+
+// readTail reads the expression following the dot of a dotted list.
+func (this *Reader) readTail() (Expression, error) {
+	start := this.prev
+	for {
+		exp, err := this.readExpression()
+		if err != nil {
+			return nil, this.syntaxError(start, ".", "Missing dotted tail", err)
+		}
+		if !isComment(exp) {
+			return exp, nil
+		}
+	}
+}
+
+// readAbbreviation reads the datum following a quote prefix such as 'x
+// and expands it into (quote x).
+func (this *Reader) readAbbreviation(start, end Position, prefix string) (List, []*Span, error) {
+	exp, err := this.readExpression()
+	if err != nil {
+		return nil, nil, this.syntaxError(start, prefix, "Missing datum", err)
+	}
+	var children []*Span
+	if this.Spans {
+		children = []*Span{{Start: start, End: end}, this.span}
+	}
+	return NewList(Identifier(abbreviations[prefix]), exp), children, nil
 }
-func (this Reader) readBinary() (Binary, error) {
+func (this *Reader) readBinary() (Binary, error) {
+	start := this.pos
 	rdr := &readerTill{
-		reader: this.Reader,
+		reader: this,
 		atEnd: func(b byte) bool {
 			if (b >= 'a' && b <= 'z') ||
 				(b >= 'A' && b <= 'Z') ||
@@ -171,94 +618,276 @@ func (this Reader) readBinary() (Binary, error) {
 		},
 	}
 
-	base64Decoder := base64.NewDecoder(base64.StdEncoding, rdr)
-	bs, err := ioutil.ReadAll(base64Decoder)
-	return Binary(bs), err
+	var decoded io.Reader = base64.NewDecoder(base64.StdEncoding, rdr)
+	if max := this.Limits.MaxAtomSize; max > 0 {
+		decoded = io.LimitReader(decoded, int64(max)+1)
+	}
+	bs, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		return nil, this.syntaxError(start, "", "Invalid binary", err)
+	}
+	if err = this.checkAtom(start, len(bs)); err != nil {
+		return nil, err
+	}
+	return Binary(bs), nil
+}
+
+// peekToken returns the text up to the next delimiter without reading it.
+func (this *Reader) peekToken() string {
+	if this.memory {
+		end := this.pos.Offset
+		for end < this.size() && !isDelimiter(rune(this.at(end))) {
+			end++
+		}
+		return this.text(this.pos.Offset, end)
+	}
+	for n := 1; ; n++ {
+		bs, _ := this.Peek(n)
+		if len(bs) < n {
+			return string(bs)
+		}
+		if n >= this.Size() || isDelimiter(rune(bs[n-1])) {
+			return string(bs[:n-1])
+		}
+	}
 }
-func (this Reader) readNumber(initial rune) (Number, error) {
+
+// readNumber reads the rest of a number whose first runes, such as a sign
+// or radix prefix, have already been read.
+func (this *Reader) readNumber(start Position, prefix string) (Number, error) {
 	var r rune
 	var err error
 
-	seenPeriod := false
-	seenNegative := false
-
-	buf := bytes.NewBuffer(make([]byte, 0, MAX_NUMBER_LENGTH))
-	buf.WriteRune(initial)
-	for buf.Len() < MAX_NUMBER_LENGTH {
-		r, _, err = this.ReadRune()
-		if r == '-' && !seenNegative {
-		} else if r == '.' && !seenPeriod {
-			seenPeriod = true
-		} else if !isDigit(r) {
-			this.UnreadRune()
+	buf := this.newAtomText(start.Offset)
+	buf.WriteString(prefix)
+	for {
+		r, err = this.readRune()
+		if err != nil {
 			break
 		}
-		buf.WriteRune(r)
-		if err != nil {
+		if isDelimiter(r) {
+			this.unreadRune()
 			break
 		}
-		// only allowed at the start
-		seenNegative = true
+		buf.WriteRune(r)
+		if err = this.checkAtom(start, buf.Len()); err != nil {
+			return "", err
+		}
+	}
+	if _, perr := parseNumber(buf.String()); perr == ErrNumberSyntax {
+		return "", this.syntaxError(start, buf.String(), "Invalid number", nil)
 	}
 
 	return Number(buf.String()), err
 }
-func (this Reader) readExpression() (Expression, error) {
+
+// readToken reads the next token, skipping whitespace and, unless they are
+// kept, comments. Atoms are read whole, lists only up to their opening.
+func (this *Reader) readToken() (Token, error) {
 	var exp Expression
 	var err error
 
+	this.begin()
 	// Skip opening whitespace
 	err = this.skipWhitespace()
+	if err == io.EOF {
+		return Token{Kind: TokenEOF, Start: this.pos, End: this.pos}, nil
+	}
 	if err != nil {
-		return exp, err
+		return Token{}, err
 	}
 
 	// Read the next character
-	r, _, err := this.ReadRune()
+	start := this.pos
+	r, err := this.readRune()
 	if err != nil {
-		return exp, err
+		return Token{}, err
+	}
+	token := func(kind TokenKind, text string) (Token, error) {
+		return Token{Kind: kind, Text: text, Start: start, End: this.pos}, nil
 	}
 
 	switch {
+	// Rivest's advanced atoms
+	case this.Advanced && this.isAdvancedAtom(r):
+		exp, err = this.readAdvancedAtom(start, r)
 	// Numbers
-	case isDigit(r) || r == '-':
-		exp, err = this.readNumber(r)
+	case isDigit(r) || (r == '+' || r == '-' || r == '.') && isNumeric(string(r)+this.peekToken()):
+		exp, err = this.readNumber(start, string(r))
+	case r == '.' && this.peekToken() == "":
+		return token(TokenDot, ".")
 	// Identifiers
 	case isLetter(r) || isExtended(r):
-		exp, err = this.readIdentifier(r)
+		var id Identifier
+		id, err = this.readIdentifier(r)
+		exp = this.Keywords.keyword(id)
 	default:
 		switch r {
 		case '(':
-			exp, err = this.readList()
+			return token(TokenOpenList, "(")
+		case ')':
+			return token(TokenCloseList, ")")
 		case '#':
-			r, _, err = this.ReadRune()
+			r, err = this.readRune()
 			if err != nil {
-				return exp, err
+				return Token{}, this.syntaxError(start, "#", "Unexpected end of input", err)
 			}
 			switch r {
 			case 't':
 				exp = True{}
 			case 'f':
 				exp = False{}
+			case 'x', 'X', 'o', 'O', 'd', 'D', 'B':
+				exp, err = this.readNumber(start, "#"+string(r))
 			case 'b':
-				exp, err = this.readBinary()
+				// binary numbers take precedence over base64 made of 0s and 1s,
+				// which is written as #b|base64| instead
+				if c, perr := this.peekByte(0); perr == nil && c == '|' {
+					this.readByte()
+					exp, err = this.readBinary()
+					if err == nil {
+						var b byte
+						if b, err = this.readByte(); err != nil || b != '|' {
+							return Token{}, this.syntaxError(start, "#b|", "Unterminated binary", err)
+						}
+					}
+				} else if isNumeric("#b" + this.peekToken()) {
+					exp, err = this.readNumber(start, "#b")
+				} else {
+					exp, err = this.readBinary()
+				}
+			case '\\':
+				exp, err = this.readChar(start)
+			case '(':
+				return token(TokenOpenList, "#(")
+			case 'u':
+				if c, _ := this.peekByte(0); c != '8' {
+					return Token{}, this.syntaxError(start, "#u", "Unknown token", nil)
+				}
+				if c, _ := this.peekByte(1); c != '(' {
+					return Token{}, this.syntaxError(start, "#u", "Unknown token", nil)
+				}
+				this.readByte()
+				this.readByte()
+				return token(TokenOpenList, "#u8(")
+			case '|':
+				var comment BlockComment
+				comment, err = this.readBlockComment(start)
+				if err != nil {
+					return Token{}, err
+				}
+				return Token{Kind: TokenComment, Value: comment, Start: start, End: this.pos}, nil
+			case ';':
+				return token(TokenDatumComment, "#;")
+			default:
+				return Token{}, this.syntaxError(start, "#"+string(r), "Unknown token", nil)
 			}
 		case '"':
 			exp, err = this.readString()
+		case '|':
+			var id Identifier
+			id, err = this.readSymbol()
+			exp = this.Keywords.keyword(id)
+		case ';':
+			var comment Comment
+			comment, err = this.readComment()
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Kind: TokenComment, Value: comment, Start: start, End: this.pos}, nil
+		case '{':
+			if !this.Advanced {
+				return Token{}, this.syntaxError(start, "{", "Unknown token", nil)
+			}
+			exp, err = this.readTransport(start)
+		case '\'', '`', ',':
+			if c, perr := this.peekByte(0); r == ',' && perr == nil && c == '@' {
+				this.readByte()
+				return token(TokenQuote, ",@")
+			}
+			return token(TokenQuote, string(r))
+		default:
+			return Token{}, this.syntaxError(start, string(r), "Unknown token", nil)
 		}
 	}
 
 	if err == io.EOF {
 		err = nil
 	}
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Kind: TokenAtom, Value: exp, Start: start, End: this.pos}, nil
+}
+
+// readFrom reads the rest of the expression starting with tok.
+func (this *Reader) readFrom(tok Token) (Expression, error) {
+	var children []*Span
+	var err error
 
-	if exp != nil {
-		return exp, err
+	defer this.leave()
+	err = this.enter()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("Unknown token %v", r)
+	exp := tok.Value
+	switch tok.Kind {
+	case TokenOpenList:
+		switch tok.Text {
+		case "(":
+			exp, children, err = this.readList()
+		case "#(":
+			exp, children, err = this.readVector(tok.Start)
+		case "#u8(":
+			exp, children, err = this.readByteVector(tok.Start)
+		}
+	case TokenCloseList:
+		return nil, this.syntaxError(tok.Start, ")", "Unknown token", nil)
+	case TokenQuote:
+		exp, children, err = this.readAbbreviation(tok.Start, tok.End, tok.Text)
+	case TokenDatumComment:
+		exp, err = this.readDatumComment(tok.Start)
+	case TokenDot:
+		// a dot only separates the tail of a dotted list
+		return nil, this.syntaxError(tok.Start, ".", "Unexpected dot", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if this.Spans {
+		this.span = &Span{Start: tok.Start, End: this.pos, Children: children}
+	}
+	return exp, nil
+}
+func (this *Reader) readExpression() (Expression, error) {
+	tok, err := this.readToken()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Kind == TokenEOF {
+		return nil, io.EOF
+	}
+	return this.readFrom(tok)
+}
+
+// Span returns the source span of the last expression read when Spans is
+// enabled.
+func (this *Reader) Span() *Span {
+	return this.span
+}
+
+func NewReader(reader io.Reader) *Reader {
+	// reuse an existing reader so buffered input isn't lost
+	if rdr, ok := reader.(*Reader); ok {
+		return rdr
+	}
+	rdr := &Reader{pos: Position{Line: 1, Column: 1}}
+	rdr.Reader = bufio.NewReader(&countingReader{src: reader, reader: rdr})
+	return rdr
 }
 
 func Read(reader io.Reader) (Expression, error) {
-	return Reader{bufio.NewReader(reader)}.readExpression()
+	return NewReader(reader).readExpression()
 }
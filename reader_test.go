@@ -1,8 +1,15 @@
 package s
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -98,6 +105,116 @@ var (
 			new(float64),
 			float64(-3.14),
 		},
+		// radix prefixes, exponents, ratios and special floats
+		{
+			`1e3`,
+			reflect.TypeOf(Number("")),
+			new(int),
+			int(1000),
+		},
+		{
+			`+5`,
+			reflect.TypeOf(Number("")),
+			new(int),
+			int(5),
+		},
+		{
+			`.5`,
+			reflect.TypeOf(Number("")),
+			new(float64),
+			float64(0.5),
+		},
+		{
+			`-1.5e-1`,
+			reflect.TypeOf(Number("")),
+			new(float64),
+			float64(-0.15),
+		},
+		{
+			`#x1F`,
+			reflect.TypeOf(Number("")),
+			new(uint8),
+			uint8(31),
+		},
+		{
+			`#o17`,
+			reflect.TypeOf(Number("")),
+			new(uint8),
+			uint8(15),
+		},
+		{
+			`#b-1010`,
+			reflect.TypeOf(Number("")),
+			new(int),
+			int(-10),
+		},
+		{
+			`#d10`,
+			reflect.TypeOf(Number("")),
+			new(int),
+			int(10),
+		},
+		{
+			`1/3`,
+			reflect.TypeOf(Number("")),
+			new(big.Rat),
+			*big.NewRat(1, 3),
+		},
+		{
+			`#xff/a`,
+			reflect.TypeOf(Number("")),
+			new(big.Rat),
+			*big.NewRat(51, 2),
+		},
+		{
+			`-inf.0`,
+			reflect.TypeOf(Number("")),
+			new(float64),
+			math.Inf(-1),
+		},
+		{
+			`+inf.0`,
+			reflect.TypeOf(Number("")),
+			new(big.Float),
+			*new(big.Float).SetInf(false),
+		},
+		// characters
+		{
+			`#\a`,
+			reflect.TypeOf(Char(0)),
+			new(rune),
+			'a',
+		},
+		{
+			`#\space`,
+			reflect.TypeOf(Char(0)),
+			new(byte),
+			byte(' '),
+		},
+		{
+			`#\x41`,
+			reflect.TypeOf(Char(0)),
+			new(string),
+			"A",
+		},
+		{
+			`#\nul`,
+			reflect.TypeOf(Char(0)),
+			new(interface{}),
+			interface{}(rune(0)),
+		},
+		{
+			`#\(`,
+			reflect.TypeOf(Char(0)),
+			new(rune),
+			'(',
+		},
+		{
+			`#\é`,
+			reflect.TypeOf(Char(0)),
+			new(rune),
+			'é',
+		},
 		// true
 		{
 			`#t`,
@@ -139,6 +256,38 @@ var (
 			new(string),
 			`test"`,
 		},
+		// vector
+		{
+			`#(1 2)`,
+			reflect.TypeOf(Vector{}),
+			new(int),
+			1,
+		},
+		{
+			`#(1 "a")`,
+			reflect.TypeOf(Vector{}),
+			new(struct {
+				N int
+				S string
+			}),
+			struct {
+				N int
+				S string
+			}{1, "a"},
+		},
+		// bytevector
+		{
+			`#u8(1 2 255)`,
+			reflect.TypeOf(ByteVector{}),
+			new([]byte),
+			[]byte{1, 2, 255},
+		},
+		{
+			`#u8()`,
+			reflect.TypeOf(ByteVector{}),
+			new(interface{}),
+			[]byte{},
+		},
 	}
 )
 
@@ -167,3 +316,352 @@ func TestReader(t *testing.T) {
 		}
 	}
 }
+
+func TestReaderNumbers(t *testing.T) {
+	type testCase struct {
+		input    string
+		expected Expression
+	}
+	testCases := []testCase{
+		{"+inf.0", Number("+inf.0")},
+		{"#X1f", Number("#X1f")},
+		{"1.", Number("1.")},
+		{"#b1010", Number("#b1010")},
+		{"#bAA==", Binary{0}},
+		{"-", Identifier("-")},
+		{"+", Identifier("+")},
+		{"...", Identifier("...")},
+		{"->x", Identifier("->x")},
+		{".foo", Identifier(".foo")},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil {
+			t.Errorf("Expected no error got %v for %q", err, tc.input)
+			continue
+		}
+		if !reflect.DeepEqual(exp, tc.expected) {
+			t.Errorf("Expected %#v got %#v for %q", tc.expected, exp, tc.input)
+		}
+	}
+
+	for _, input := range []string{"1/0", "1e", "#x1.5", "-1x", ".5.5", "#o8"} {
+		_, err := Read(bytes.NewBufferString(input))
+		if serr, ok := err.(*SyntaxError); !ok || serr.Msg != "Invalid number" {
+			t.Errorf("Expected an invalid number error got %v for %q", err, input)
+		}
+	}
+
+	var v float64
+	err := Number("+nan.0").Scan(&v)
+	if err != nil || !math.IsNaN(v) {
+		t.Errorf("Expected NaN got %v, %v", v, err)
+	}
+}
+
+func TestReaderStrings(t *testing.T) {
+	type testCase struct {
+		input    string
+		expected String
+	}
+	testCases := []testCase{
+		{`"a\tb\0"`, "a\tb\x00"},
+		{`"\x41;\x1F600;"`, "A\U0001F600"},
+		{`"\u00e9\U0001F600\ud83d\ude00"`, "é\U0001F600\U0001F600"},
+		{"\"a \\  \n   b\"", "a b"},
+		{"\"a\\\r\nb\"", "ab"},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil || exp != tc.expected {
+			t.Errorf("Expected %q got %v, %v for %v", tc.expected, exp, err, tc.input)
+		}
+	}
+
+	invalid := map[string]string{
+		`"\q"`:           `\q`,
+		`"\x;"`:          `\x;`,
+		`"\x110000;"`:    `\x110000;`,
+		`"\u12G4"`:       `\u12G`,
+		`"\ud83d"`:       `\ud83d"`,
+		`"\ude00"`:       `\ude00`,
+		`"\ud83d\u0041"`: `\ud83d\u0041`,
+		`"\UFFFFFFFF"`:   `\UFFFFFFFF`,
+		`"\ a"`:          `\ a`,
+	}
+	for input, token := range invalid {
+		_, err := Read(bytes.NewBufferString(input))
+		serr, ok := err.(*SyntaxError)
+		if !ok || serr.Msg != "Invalid escape" || serr.Token != token {
+			t.Errorf("Expected an invalid escape `%v` got %v for %v", token, err, input)
+		}
+	}
+}
+
+func TestReaderVectors(t *testing.T) {
+	type testCase struct {
+		input    string
+		expected Expression
+	}
+	testCases := []testCase{
+		{"#(1 a \"b\")", Vector{Number("1"), Identifier("a"), String("b")}},
+		{"#()", Vector{}},
+		{"#(#(1) (2))", Vector{Vector{Number("1")}, NewList(Number("2"))}},
+		{"#u8(0 #xff 16)", ByteVector{0, 255, 16}},
+		{"#u8()", ByteVector{}},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil {
+			t.Errorf("Expected no error got %v for %q", err, tc.input)
+			continue
+		}
+		if !reflect.DeepEqual(exp, tc.expected) {
+			t.Errorf("Expected %#v got %#v for %q", tc.expected, exp, tc.input)
+		}
+		if fmt.Sprint(exp) != strings.Replace(tc.input, "#xff", "255", 1) {
+			t.Errorf("Expected %v to write as %q", exp, tc.input)
+		}
+	}
+
+	invalid := map[string]string{
+		"#(1 . 2)":    "Unexpected dot in vector",
+		"#u8(1 256)":  "Invalid byte",
+		"#u8(1 a)":    "Invalid byte",
+		"#u16(1)":     "Unknown token",
+		"#u8(1 (2))":  "Invalid byte",
+		"#u8(1 -1.5)": "Invalid byte",
+	}
+	for input, msg := range invalid {
+		_, err := Read(bytes.NewBufferString(input))
+		if serr, ok := err.(*SyntaxError); !ok || serr.Msg != msg {
+			t.Errorf("Expected %v got %v for %q", msg, err, input)
+		}
+	}
+}
+
+func TestReaderSymbols(t *testing.T) {
+	type testCase struct {
+		input    string
+		expected Expression
+	}
+	testCases := []testCase{
+		{`|a b|`, Identifier("a b")},
+		{`|a\|b\x41;|`, Identifier("a|bA")},
+		{`||`, Identifier("")},
+		{`(|x| y)`, NewList(Identifier("x"), Identifier("y"))},
+		{`(λ (größe) été)`, NewList(Identifier("λ"), NewList(Identifier("größe")), Identifier("été"))},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil || !reflect.DeepEqual(exp, tc.expected) {
+			t.Errorf("Expected %#v got %#v, %v for %v", tc.expected, exp, err, tc.input)
+		}
+	}
+
+	_, err := Read(bytes.NewBufferString(`|abc`))
+	if serr, ok := err.(*SyntaxError); !ok || serr.Msg != "Unterminated symbol" {
+		t.Errorf("Expected an unterminated symbol got %v", err)
+	}
+}
+
+func TestReaderSyntaxError(t *testing.T) {
+	type testCase struct {
+		input string
+		token string
+		pos   Position
+	}
+	testCases := []testCase{
+		{"}", "}", Position{0, 1, 1}},
+		{"(a\n  b ])", "]", Position{7, 2, 5}},
+		{"(a #z)", "#z", Position{3, 1, 4}},
+		{"\"é\" 12ab", "12ab", Position{5, 1, 5}},
+		{"(a\n(b", "(", Position{3, 2, 1}},
+		{"\n #bYQ)", "", Position{4, 2, 4}},
+		{"(1-2)", "1-2", Position{1, 1, 2}},
+		{"(#\\spade)", "#\\spade", Position{1, 1, 2}},
+		{"a . b", ".", Position{2, 1, 3}},
+	}
+	for _, tc := range testCases {
+		rdr := NewReader(bytes.NewBufferString(tc.input))
+		var err error
+		for err == nil {
+			_, err = Read(rdr)
+		}
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Expected a SyntaxError got %v for %q", err, tc.input)
+			continue
+		}
+		if serr.Token != tc.token || serr.Position != tc.pos {
+			t.Errorf("Expected `%v` at %#v got `%v` at %#v for %q", tc.token, tc.pos, serr.Token, serr.Position, tc.input)
+		}
+	}
+}
+
+func TestZeroReaderPositions(t *testing.T) {
+	rdr := &Reader{Reader: bufio.NewReader(bytes.NewBufferString("(a\n ]"))}
+	_, err := Read(rdr)
+	serr, ok := err.(*SyntaxError)
+	if !ok || serr.Position != (Position{4, 2, 2}) {
+		t.Errorf("Expected a SyntaxError at line 2, column 2 got %v", err)
+	}
+}
+
+func TestReaderSpans(t *testing.T) {
+	rdr := NewReader(bytes.NewBufferString("  (define\n  x \"é\")"))
+	rdr.Spans = true
+	_, err := Read(rdr)
+	if err != nil {
+		t.Fatalf("Expected no error got %v", err)
+	}
+	expected := &Span{
+		Start: Position{2, 1, 3},
+		End:   Position{19, 2, 9},
+		Children: []*Span{
+			{Start: Position{3, 1, 4}, End: Position{9, 1, 10}},
+			{Start: Position{12, 2, 3}, End: Position{13, 2, 4}},
+			{Start: Position{14, 2, 5}, End: Position{18, 2, 8}},
+		},
+	}
+	if !reflect.DeepEqual(rdr.Span(), expected) {
+		t.Errorf("Expected %v got %v", expected, rdr.Span())
+	}
+}
+
+func TestReaderComments(t *testing.T) {
+	input := `; leading
+(a ; trailing
+ #| block #| nested |# |# b
+ #;(c d) e) #;f`
+	expected := NewList(Identifier("a"), Identifier("b"), Identifier("e"))
+
+	rdr := NewReader(bytes.NewBufferString(input))
+	exp, err := Read(rdr)
+	if err != nil || !reflect.DeepEqual(exp, expected) {
+		t.Errorf("Expected %v got %v, %v", expected, exp, err)
+	}
+	_, err = Read(rdr)
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF got %v", err)
+	}
+
+	rdr = NewReader(bytes.NewBufferString(input))
+	rdr.Comments = true
+	var exps []Expression
+	for {
+		exp, err := Read(rdr)
+		if err != nil {
+			break
+		}
+		exps = append(exps, exp)
+	}
+	expected = NewList(
+		Comment(" leading"),
+		NewList(
+			Identifier("a"), Comment(" trailing"),
+			BlockComment(" block #| nested |# "), Identifier("b"),
+			DatumComment{NewList(Identifier("c"), Identifier("d"))}, Identifier("e"),
+		),
+		DatumComment{Identifier("f")},
+	)
+	if !reflect.DeepEqual(NewList(exps...), expected) {
+		t.Errorf("Expected %v got %v", expected, exps)
+	}
+
+	var names []string
+	err = Unmarshal(exps[1], &names)
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b", "e"}) {
+		t.Errorf("Expected [a b e] got %v, %v", names, err)
+	}
+
+	_, err = Read(bytes.NewBufferString("(a #| b)"))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF got %v", err)
+	}
+
+	for _, input := range []string{"(a #;)", "(a #;", "#;"} {
+		_, err = Read(bytes.NewBufferString(input))
+		if serr, ok := err.(*SyntaxError); !ok || serr.Msg != "Missing datum" {
+			t.Errorf("Expected a missing datum got %v for %q", err, input)
+		}
+	}
+
+	rdr = NewReader(bytes.NewBufferString("; a\r\n; b\r"))
+	rdr.Comments = true
+	for _, expected := range []Comment{" a", " b"} {
+		exp, err = Read(rdr)
+		if err != nil || exp != expected {
+			t.Errorf("Expected %q got %q, %v", expected, exp, err)
+		}
+	}
+}
+
+func TestReaderPairs(t *testing.T) {
+	type testCase struct {
+		input  string
+		result Expression
+	}
+	testCases := []testCase{
+		{`(a . b)`, Pair{Identifier("a"), Identifier("b")}},
+		{`(1 2 . rest)`, Pair{Number("1"), Pair{Number("2"), Identifier("rest")}}},
+		{`(a . (b c))`, NewList(Identifier("a"), Identifier("b"), Identifier("c"))},
+		{`(a . ())`, NewList(Identifier("a"))},
+		{`(a.b .c ...)`, NewList(Identifier("a.b"), Identifier(".c"), Identifier("..."))},
+		{`((a . 1) (b . "x"))`, NewList(Pair{Identifier("a"), Number("1")}, Pair{Identifier("b"), String("x")})},
+	}
+	for _, tc := range testCases {
+		exp, err := Read(bytes.NewBufferString(tc.input))
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.input)
+			continue
+		}
+		if !reflect.DeepEqual(exp, tc.result) {
+			t.Errorf("Expected %v got %v for %v", tc.result, exp, tc.input)
+		}
+	}
+
+	for _, input := range []string{`(. a)`, `(a . b c)`, `(a . )`, `(a . b . c)`} {
+		_, err := Read(bytes.NewBufferString(input))
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Expected a SyntaxError got %v for %v", err, input)
+		}
+	}
+
+	var name string
+	var value int
+	err := Pair{Identifier("a"), Number("1")}.Scan(&name, &value)
+	if err != nil || name != "a" || value != 1 {
+		t.Errorf("Expected a 1 got %v %v, %v", name, value, err)
+	}
+	var entry struct {
+		Name  string
+		Value int
+	}
+	err = Unmarshal(Pair{Identifier("b"), Number("2")}, &entry)
+	if err != nil || entry.Name != "b" || entry.Value != 2 {
+		t.Errorf("Expected {b 2} got %v, %v", entry, err)
+	}
+
+	// pairs can't be held by scalars
+	pair := Pair{Identifier("a"), Number("1")}
+	if err = pair.Scan(&name); err == nil {
+		t.Errorf("Expected an error scanning a pair into a string got %q", name)
+	}
+	if err = Unmarshal(pair, &value); err == nil {
+		t.Errorf("Expected an error unmarshalling a pair into an int got %v", value)
+	}
+	var ptr *int
+	if err = Unmarshal(pair, &ptr); err == nil {
+		t.Errorf("Expected an error unmarshalling a pair into a *int")
+	}
+	var n big.Int
+	if err = Unmarshal(pair, &n); err == nil {
+		t.Errorf("Expected an error unmarshalling a pair into a big.Int got %v", &n)
+	}
+	var names []string
+	err = NewDecoder(bytes.NewBufferString("((a . 1))")).DecodeInto(&names)
+	if err == nil {
+		t.Errorf("Expected an error decoding pairs into []string got %v", names)
+	}
+}
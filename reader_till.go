@@ -1,12 +1,12 @@
 package s
 
 import (
-	"bufio"
+	"io"
 )
 
 type (
 	readerTill struct {
-		reader *bufio.Reader
+		reader *Reader
 		atEnd  func(byte) bool
 	}
 )
@@ -14,21 +14,21 @@ type (
 func (this *readerTill) Read(p []byte) (int, error) {
 	var err error
 	read := 0
-	var tmp []byte
 
 	for i := 0; i < len(p); i++ {
-		tmp, err = this.reader.Peek(1)
-		if len(tmp) > 0 {
-			if this.atEnd(tmp[0]) {
-				break
-			}
-			p[i] = tmp[0]
-			read++
-			this.reader.Read(tmp)
-		}
+		var b byte
+		b, err = this.reader.peekByte(0)
 		if err != nil {
 			break
 		}
+		if this.atEnd(b) {
+			// the end is reported as EOF so callers stop reading
+			err = io.EOF
+			break
+		}
+		p[i] = b
+		read++
+		this.reader.readByte()
 	}
 
 	return read, err
@@ -3,12 +3,15 @@ package s
 import (
 	"fmt"
 	"io"
-	"strconv"
-	"strings"
+	"math"
+	"math/big"
 	"reflect"
+	"strconv"
 )
 
-func (this List) Scan(dst ... interface{}) error {
+func (this List) Scan(dst ...interface{}) error {
+	this = this.WithoutComments()
+
 	// do nothing if the list is empty
 	if len(this) == 0 {
 		return nil
@@ -17,21 +20,13 @@ func (this List) Scan(dst ... interface{}) error {
 	// one argument
 	if len(dst) == 1 {
 		val := reflect.ValueOf(dst[0])
-		// pointer to a struct
+		// pointer to a struct or map
 		if val.Kind() == reflect.Ptr {
-			if e := val.Elem(); e.Kind() == reflect.Struct {
-				n := len(this)
-				if n > e.NumField() {
-					n = e.NumField()
-				}
-				for i := 0; i < n; i++ {
-					f := e.Field(i).Addr().Interface()
-					err := this[i].Scan(f)
-					if err != nil {
-						return err
-					}
-				}
-				return nil
+			switch e := val.Elem(); e.Kind() {
+			case reflect.Struct:
+				return DecodeOptions{}.decodeStruct(this, e)
+			case reflect.Map:
+				return DecodeOptions{}.decodeValue(this, e)
 			}
 		}
 	}
@@ -48,7 +43,30 @@ func (this List) Scan(dst ... interface{}) error {
 	}
 	return nil
 }
-func (this String) Scan(dsts ... interface{}) error {
+func (this Pair) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	if len(dsts) == 1 {
+		// only containers can hold both halves of the pair
+		if val := reflect.ValueOf(dsts[0]); val.Kind() == reflect.Ptr && !val.IsNil() {
+			switch val.Elem().Kind() {
+			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+				return Unmarshal(this, dsts[0])
+			}
+			if val.Type().Implements(decoderType) {
+				return Unmarshal(this, dsts[0])
+			}
+		}
+		return fmt.Errorf("Cannot convert pair into %T", dsts[0])
+	}
+	err := this.Car.Scan(dsts[0])
+	if err != nil {
+		return err
+	}
+	return this.Cdr.Scan(dsts[1])
+}
+func (this String) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
@@ -69,7 +87,7 @@ func (this String) Scan(dsts ... interface{}) error {
 	}
 	return err
 }
-func (this Binary) Scan(dsts ... interface{}) error {
+func (this Binary) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
@@ -88,7 +106,26 @@ func (this Binary) Scan(dsts ... interface{}) error {
 	}
 	return err
 }
-func (this True) Scan(dsts ... interface{}) error {
+func (this ByteVector) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	dst := dsts[0]
+
+	switch t := dst.(type) {
+	case *interface{}:
+		*t = []byte(this)
+	case *[]byte:
+		*t = []byte(this)
+	default:
+		return fmt.Errorf("Cannot convert bytevector into %T", dst)
+	}
+	return nil
+}
+func (this Vector) Scan(dsts ...interface{}) error {
+	return List(this).Scan(dsts...)
+}
+func (this True) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
@@ -104,7 +141,7 @@ func (this True) Scan(dsts ... interface{}) error {
 	}
 	return nil
 }
-func (this False) Scan(dsts ... interface{}) error {
+func (this False) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
@@ -120,102 +157,182 @@ func (this False) Scan(dsts ... interface{}) error {
 	}
 	return nil
 }
-func (this Number) Scan(dsts ... interface{}) error {
+func (this Keyword) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
 	dst := dsts[0]
+	switch t := dst.(type) {
+	case *interface{}:
+		*t = string(this)
+	case *string:
+		*t = string(this)
+	default:
+		return fmt.Errorf("Cannot convert keyword into %T", dst)
+	}
+	return nil
+}
+func (this Number) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	return this.ScanMode(dsts[0], NumberSaturate)
+}
 
-	var vi int64
-	var vui uint64
-	var vf float64
-
-	str := string(this)
-
-	p := strings.IndexRune(str, '.')
-	if p == -1 {
-		vi, _ = strconv.ParseInt(str, 10, 64)
-		vui, _ = strconv.ParseUint(str, 10, 64)
-	} else {
-		vi, _ = strconv.ParseInt(str[:p], 10, 64)
-		vui, _ = strconv.ParseUint(str[:p], 10, 64)
-	}
-	vf, _ = strconv.ParseFloat(str, 64)
+// ScanMode is like Scan, with mode controlling values which don't fit dst.
+// Into an interface{} integers scan as int64, or *big.Int when too large,
+// ratios as *big.Rat and decimals, infinities and NaN as float64.
+func (this Number) ScanMode(dst interface{}, mode NumberMode) error {
+	r, err := parseNumber(string(this))
+	if err != nil {
+		return this.error(dst, err)
+	}
 
 	switch t := dst.(type) {
 	case *interface{}:
-		if p == -1 {
-			*t = vi
-		} else {
-			*t = vf
+		switch {
+		case r.inexact:
+			*t, err = float(r, 64, NumberSaturate)
+		case !r.rat.IsInt():
+			*t = r.rat
+		case r.rat.Num().IsInt64():
+			*t = r.rat.Num().Int64()
+		default:
+			*t = new(big.Int).Set(r.rat.Num())
 		}
 	case *uint8:
-		var max uint8 = 1<<8 - 1
-		if vui > uint64(max) {
-			*t = max
-		} else {
-			*t = uint8(vui)
-		}
+		var v uint64
+		v, err = unsignedInteger(r, 8, mode)
+		*t = uint8(v)
 	case *uint16:
-		var max uint16 = 1<<16 - 1
-		if vui > uint64(max) {
-			*t = max
-		} else {
-			*t = uint16(vui)
-		}
+		var v uint64
+		v, err = unsignedInteger(r, 16, mode)
+		*t = uint16(v)
 	case *uint32:
-		var max uint32 = 1<<32 - 1
-		if vui > uint64(max) {
-			*t = max
-		} else {
-			*t = uint32(vui)
-		}
+		var v uint64
+		v, err = unsignedInteger(r, 32, mode)
+		*t = uint32(v)
 	case *uint64:
-		*t = vui
+		*t, err = unsignedInteger(r, 64, mode)
+	case *uint:
+		var v uint64
+		v, err = unsignedInteger(r, strconv.IntSize, mode)
+		*t = uint(v)
+	case *uintptr:
+		var v uint64
+		v, err = unsignedInteger(r, strconv.IntSize, mode)
+		*t = uintptr(v)
 	case *int8:
-		var max int8 = 1<<7 - 1
-		var min = -max - 1
-		if vi > int64(max) {
-			*t = max
-		} else if vi < int64(min) {
-			*t = min
-		} else {
-			*t = int8(vi)
-		}
+		var v int64
+		v, err = signedInteger(r, 8, mode)
+		*t = int8(v)
 	case *int16:
-		var max int16 = 1<<15 - 1
-		var min = -max - 1
-		if vi > int64(max) {
-			*t = max
-		} else if vi < int64(min) {
-			*t = min
-		} else {
-			*t = int16(vi)
-		}
+		var v int64
+		v, err = signedInteger(r, 16, mode)
+		*t = int16(v)
 	case *int32:
-		var max int32 = 1<<31 - 1
-		var min = -max - 1
-		if vi > int64(max) {
-			*t = max
-		} else if vi < int64(min) {
-			*t = min
-		} else {
-			*t = int32(vi)
-		}
+		var v int64
+		v, err = signedInteger(r, 32, mode)
+		*t = int32(v)
 	case *int64:
-		*t = vi
+		*t, err = signedInteger(r, 64, mode)
 	case *int:
-		*t = int(vi)
+		var v int64
+		v, err = signedInteger(r, strconv.IntSize, mode)
+		*t = int(v)
 	case *float32:
-		*t = float32(vf)
+		var v float64
+		v, err = float(r, 32, mode)
+		*t = float32(v)
 	case *float64:
-		*t = vf
+		*t, err = float(r, 64, mode)
+	case *big.Int:
+		// big.Int has no bound to saturate to, so fractions are never
+		// truncated
+		var v *big.Int
+		v, err = integer(r, nil, nil, NumberStrict)
+		if err == nil {
+			t.Set(v)
+		}
+	case *big.Float:
+		switch {
+		case r.rat != nil:
+			t.SetRat(r.rat)
+		case math.IsNaN(r.float), r.saturated && mode == NumberStrict:
+			err = ErrNumberRange
+		case math.IsInf(r.float, 0):
+			t.SetInf(r.float < 0)
+		default:
+			t.SetFloat64(r.float)
+		}
+	case *big.Rat:
+		if r.rat == nil {
+			err = ErrNumberRange
+		} else {
+			t.Set(r.rat)
+		}
 	default:
 		return fmt.Errorf("Cannot convert number to %T", dst)
 	}
+	if err != nil {
+		return this.error(dst, err)
+	}
 	return nil
 }
-func (this Identifier) Scan(dsts ... interface{}) error {
+func (this Number) error(dst interface{}, err error) error {
+	typ := reflect.TypeOf(dst)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return &NumberError{Number: this, Type: typ, Err: err}
+}
+func (this BlockComment) Scan(dsts ...interface{}) error {
+	return Comment(this).Scan(dsts...)
+}
+func (this Char) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	dst := dsts[0]
+	switch t := dst.(type) {
+	case *interface{}:
+		*t = rune(this)
+	case *rune:
+		*t = rune(this)
+	case *byte:
+		if this > 0xFF {
+			return fmt.Errorf("Cannot convert character %v into byte", this)
+		}
+		*t = byte(this)
+	case *string:
+		*t = string(this)
+	default:
+		return fmt.Errorf("Cannot convert character into %T", dst)
+	}
+	return nil
+}
+func (this Comment) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	dst := dsts[0]
+	switch t := dst.(type) {
+	case *interface{}:
+		*t = string(this)
+	case *string:
+		*t = string(this)
+	default:
+		return fmt.Errorf("Cannot convert comment into %T", dst)
+	}
+	return nil
+}
+func (this DatumComment) Scan(dsts ...interface{}) error {
+	if len(dsts) == 0 {
+		return fmt.Errorf("Expected at least one argument")
+	}
+	return fmt.Errorf("Cannot convert datum comment into %T", dsts[0])
+}
+func (this Identifier) Scan(dsts ...interface{}) error {
 	if len(dsts) == 0 {
 		return fmt.Errorf("Expected at least one argument")
 	}
@@ -0,0 +1,96 @@
+package s
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+)
+
+type (
+	StreamDecoder struct {
+		reader  *Reader
+		options DecodeOptions
+	}
+	StreamEncoder struct {
+		dst       io.Writer
+		writer    *bufio.Writer
+		separator string
+		options   EncodeOptions
+	}
+)
+
+// NewDecoder returns a decoder which reads a sequence of expressions from
+// reader. Input buffered past the end of one expression is kept for the next.
+func NewDecoder(reader io.Reader) *StreamDecoder {
+	return &StreamDecoder{reader: NewReader(reader)}
+}
+
+// Decode reads the next expression. It returns io.EOF when the stream ends
+// cleanly and io.ErrUnexpectedEOF when it ends inside an expression.
+func (this *StreamDecoder) Decode() (Expression, error) {
+	return this.reader.readExpression()
+}
+
+// DecodeInto reads the next expression and unmarshals it into v. Comments
+// are skipped.
+func (this *StreamDecoder) DecodeInto(v interface{}) error {
+	for {
+		exp, err := this.Decode()
+		if err != nil {
+			return err
+		}
+		if !isComment(exp) {
+			return this.options.Unmarshal(exp, v)
+		}
+	}
+}
+
+// SetOptions sets the options DecodeInto unmarshals with.
+func (this *StreamDecoder) SetOptions(options DecodeOptions) {
+	this.options = options
+}
+
+// SetLimits sets the limits on the input read, see Limits.
+func (this *StreamDecoder) SetLimits(limits Limits) {
+	this.reader.Limits = limits
+}
+
+// More reports whether there is another expression in the stream.
+func (this *StreamDecoder) More() bool {
+	return this.reader.skipWhitespace() == nil
+}
+
+// NewEncoder returns an encoder which writes each value as a top-level
+// expression followed by a newline.
+func NewEncoder(writer io.Writer) *StreamEncoder {
+	return &StreamEncoder{
+		dst:       writer,
+		writer:    bufio.NewWriter(writer),
+		separator: "\n",
+	}
+}
+
+// SetSeparator sets the text written after each expression.
+func (this *StreamEncoder) SetSeparator(separator string) {
+	this.separator = separator
+}
+
+// SetOptions sets the options values are encoded with.
+func (this *StreamEncoder) SetOptions(options EncodeOptions) {
+	this.options = options
+}
+
+// Encode writes v using the same rules as Encode, without building the
+// expression in memory first. If encoding fails, output not yet flushed
+// to the underlying writer is discarded.
+func (this *StreamEncoder) Encode(v interface{}) error {
+	err := this.options.encodeValue(this.options.sink(&writerSink{dst: this.writer}), reflect.ValueOf(v))
+	if err == nil {
+		_, err = io.WriteString(this.writer, this.separator)
+	}
+	if err != nil {
+		this.writer.Reset(this.dst)
+		return err
+	}
+	return this.writer.Flush()
+}
@@ -0,0 +1,93 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("(a 1)\n\"x\" 3 #t\n(b 2)\n"))
+	expected := []Expression{
+		NewList(Identifier("a"), Number("1")),
+		String("x"),
+		Number("3"),
+		True{},
+	}
+	for _, e := range expected {
+		if !dec.More() {
+			t.Fatalf("Expected more expressions before %v", e)
+		}
+		exp, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Expected no error got %v for %v", err, e)
+		}
+		if !reflect.DeepEqual(exp, e) {
+			t.Errorf("Expected %v got %v", e, exp)
+		}
+	}
+
+	var v struct {
+		Name  string
+		Value int
+	}
+	err := dec.DecodeInto(&v)
+	if err != nil || v.Name != "b" || v.Value != 2 {
+		t.Errorf("Expected {b 2} got %v, %v", v, err)
+	}
+
+	if dec.More() {
+		t.Errorf("Expected no more expressions")
+	}
+	_, err = dec.Decode()
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF got %v", err)
+	}
+}
+
+func TestStreamDecoderTruncated(t *testing.T) {
+	for _, input := range []string{"(a (b", `"abc`, "#"} {
+		_, err := NewDecoder(strings.NewReader(input)).Decode()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Expected io.ErrUnexpectedEOF got %v for %v", err, input)
+		}
+	}
+}
+
+func TestStreamEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	values := []interface{}{
+		struct{ x, y int }{25, 34},
+		map[string][]int{"a": {1, 2}},
+		[]interface{}{"a", nil, []int{}, Identifier("b")},
+		decoderHook{"hook"},
+		true,
+	}
+	for _, v := range values {
+		err := enc.Encode(v)
+		if err != nil {
+			t.Fatalf("Expected no error got %v for %v", err, v)
+		}
+	}
+	enc.SetSeparator(" ")
+	enc.Encode(1)
+	enc.Encode(2)
+
+	expected := "(25 34)\n((\"a\" (1 2)))\n(\"a\" () () b)\nhook\n#t\n1 2 "
+	if buf.String() != expected {
+		t.Errorf("Expected %q got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	err := enc.Encode([]interface{}{1, make(chan int)})
+	if err == nil {
+		t.Errorf("Expected an error for a channel")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output got %q", buf.String())
+	}
+}
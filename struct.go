@@ -0,0 +1,289 @@
+package s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	structStyle int
+	structField struct {
+		index     int
+		name      string
+		pos       int
+		omitEmpty bool
+	}
+	structInfo struct {
+		style  structStyle
+		fields []structField
+		length int
+		err    error
+	}
+)
+
+const (
+	// (value ...)
+	positionalStruct structStyle = iota
+	// ((name value) ...)
+	keyedStruct
+	// (name: value ...)
+	plistStruct
+)
+
+var structInfos sync.Map
+
+// getStructInfo parses the `s:"name,options"` tags of a struct type. The
+// options are:
+//
+//	omitempty  leave the field out when it has its zero value
+//	pos=N      put the field at position N of a positional list
+//
+// A blank field tagged `s:",keyed"` or `s:",plist"` switches the struct
+// from a positional list to ((name value) ...) or (name: value ...).
+func getStructInfo(typ reflect.Type) (*structInfo, error) {
+	if info, ok := structInfos.Load(typ); ok {
+		return info.(*structInfo), info.(*structInfo).err
+	}
+
+	info := &structInfo{}
+	pos := -1
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, hasTag := f.Tag.Lookup("s")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if f.Name == "_" && hasTag {
+			for _, opt := range opts[1:] {
+				switch opt {
+				case "keyed":
+					info.style = keyedStruct
+				case "plist":
+					info.style = plistStruct
+				}
+			}
+			continue
+		}
+
+		field := structField{index: i, name: f.Name, pos: pos + 1}
+		if opts[0] != "" {
+			field.name = opts[0]
+		}
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "omitempty":
+				field.omitEmpty = true
+			case strings.HasPrefix(opt, "pos="):
+				n, err := strconv.Atoi(opt[len("pos="):])
+				if err != nil || n < 0 {
+					info.err = fmt.Errorf("Invalid position `%v` for field %v of %v", opt, f.Name, typ)
+				}
+				field.pos = n
+			}
+		}
+		pos = field.pos
+		info.fields = append(info.fields, field)
+	}
+
+	if info.style == positionalStruct {
+		sort.SliceStable(info.fields, func(i, j int) bool {
+			return info.fields[i].pos < info.fields[j].pos
+		})
+		for i, field := range info.fields {
+			if i > 0 && info.fields[i-1].pos == field.pos && info.err == nil {
+				info.err = fmt.Errorf("Duplicate position %v for field %v of %v", field.pos, typ.Field(field.index).Name, typ)
+			}
+			info.length = field.pos + 1
+		}
+	} else {
+		// unexported fields are only encoded positionally
+		fields := info.fields[:0]
+		for _, field := range info.fields {
+			if typ.Field(field.index).PkgPath == "" {
+				fields = append(fields, field)
+			}
+		}
+		info.fields = fields
+	}
+
+	structInfos.Store(typ, info)
+	return info, info.err
+}
+
+func (this *structInfo) field(name string) (structField, bool) {
+	for _, field := range this.fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	for _, field := range this.fields {
+		if strings.EqualFold(field.name, name) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+func (this EncodeOptions) encodeStruct(sink encodeSink, src reflect.Value) error {
+	info, err := getStructInfo(src.Type())
+	if err != nil {
+		return err
+	}
+
+	err = sink.beginList()
+	if err != nil {
+		return err
+	}
+	switch info.style {
+	case positionalStruct:
+		// trailing empty fields can be left out without moving the others
+		length := info.length
+		for i := len(info.fields) - 1; i >= 0; i-- {
+			field := info.fields[i]
+			if field.pos+1 < length || !field.omitEmpty || !src.Field(field.index).IsZero() {
+				break
+			}
+			length = field.pos
+		}
+		next := 0
+		for _, field := range info.fields {
+			if field.pos >= length {
+				break
+			}
+			for ; next < field.pos; next++ {
+				err = sink.expression(NewList())
+				if err != nil {
+					return err
+				}
+			}
+			err = this.encodeValue(sink, src.Field(field.index))
+			if err != nil {
+				return err
+			}
+			next++
+		}
+	default:
+		for _, field := range info.fields {
+			f := src.Field(field.index)
+			if field.omitEmpty && f.IsZero() {
+				continue
+			}
+			if info.style == keyedStruct {
+				err = sink.beginList()
+				if err == nil {
+					err = sink.expression(Identifier(field.name))
+				}
+				if err == nil {
+					err = this.encodeValue(sink, f)
+				}
+				if err == nil {
+					err = sink.endList()
+				}
+			} else {
+				err = sink.expression(Identifier(field.name + ":"))
+				if err == nil {
+					err = this.encodeValue(sink, f)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return sink.endList()
+}
+
+func (this DecodeOptions) decodeStruct(src List, dst reflect.Value) error {
+	info, err := getStructInfo(dst.Type())
+	if err != nil {
+		return err
+	}
+
+	set := func(field structField, exp Expression) error {
+		f := dst.Field(field.index)
+		// unexported fields are encoded, but can't be set
+		if !f.CanSet() {
+			return nil
+		}
+		return this.decodeValue(exp, f)
+	}
+
+	switch info.style {
+	case positionalStruct:
+		// keyword arguments may follow the positional ones, starting with
+		// the keyword of a field followed by its value. Other keywords are
+		// positional values.
+		positional := src
+		for i, exp := range src {
+			name, ok := exp.(Keyword)
+			if !ok || i+1 == len(src) {
+				continue
+			}
+			if _, ok = info.field(string(name)); ok {
+				positional = src[:i]
+				err = decodeKeywords(info, src[i:], true, set)
+				if err != nil {
+					return err
+				}
+				break
+			}
+		}
+		for _, field := range info.fields {
+			if field.pos >= len(positional) {
+				break
+			}
+			err = set(field, positional[field.pos])
+			if err != nil {
+				return err
+			}
+		}
+	case keyedStruct:
+		for _, exp := range src {
+			var name string
+			entry, ok := exp.(List)
+			if !ok || len(entry) != 2 || entry[0].Scan(&name) != nil {
+				return fmt.Errorf("Expected (name value) got %v", exp)
+			}
+			if field, ok := info.field(name); ok {
+				err = set(field, entry[1])
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case plistStruct:
+		return decodeKeywords(info, src, false, set)
+	}
+	return nil
+}
+
+// decodeKeywords sets fields from name: value or :name value pairs. Names
+// which aren't fields are skipped, or are errors when strict.
+func decodeKeywords(info *structInfo, src List, strict bool, set func(structField, Expression) error) error {
+	if len(src)%2 != 0 {
+		return fmt.Errorf("Expected name: value pairs got %v", src)
+	}
+	for i := 0; i < len(src); i += 2 {
+		name, ok := keyName(src[i])
+		if !ok {
+			return fmt.Errorf("Expected name: got %v", src[i])
+		}
+		field, ok := info.field(name)
+		if !ok {
+			if strict {
+				return fmt.Errorf("Unknown keyword %v", src[i])
+			}
+			continue
+		}
+		err := set(field, src[i+1])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package s
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type (
+	tagSkip struct {
+		A      int
+		Secret string `s:"-"`
+		B      int
+	}
+	tagPositions struct {
+		A int
+		C int `s:",pos=2"`
+		D int
+		B int `s:",pos=1"`
+	}
+	tagOmitTrailing struct {
+		A int
+		B []int `s:",omitempty"`
+	}
+	tagKeyed struct {
+		_     struct{} `s:",keyed"`
+		Host  string   `s:"host"`
+		Port  int      `s:"port,omitempty"`
+		Debug bool
+	}
+	tagPlist struct {
+		_    struct{} `s:",plist"`
+		Host string   `s:"host"`
+		Port int      `s:"port"`
+	}
+	tagDuplicate struct {
+		A int
+		B int `s:",pos=0"`
+	}
+)
+
+func TestStructTags(t *testing.T) {
+	type testCase struct {
+		Value  interface{}
+		Result string
+	}
+	testCases := []testCase{
+		{tagSkip{1, "x", 2}, "(1 2)"},
+		{tagPositions{1, 3, 4, 2}, "(1 2 3 4)"},
+		{tagOmitTrailing{1, nil}, "(1)"},
+		{tagOmitTrailing{1, []int{2}}, "(1 (2))"},
+		{tagKeyed{Host: "x", Port: 80}, `((host "x") (port 80) (Debug #f))`},
+		{tagKeyed{Host: "x"}, `((host "x") (Debug #f))`},
+		{tagPlist{Host: "x", Port: 80}, `(host: "x" port: 80)`},
+	}
+	for _, tc := range testCases {
+		exp, err := Encode(tc.Value)
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, tc.Value)
+			continue
+		}
+		if fmt.Sprint(exp) != tc.Result {
+			t.Errorf("Expected `%v` got `%v` for %v", tc.Result, exp, tc.Value)
+		}
+		dst := reflect.New(reflect.TypeOf(tc.Value))
+		err = Unmarshal(exp, dst.Interface())
+		if err != nil {
+			t.Errorf("Expected no error got %v for %v", err, exp)
+			continue
+		}
+		expected := reflect.ValueOf(tc.Value)
+		if v, ok := tc.Value.(tagSkip); ok {
+			v.Secret = ""
+			expected = reflect.ValueOf(v)
+		}
+		if !reflect.DeepEqual(dst.Elem().Interface(), expected.Interface()) {
+			t.Errorf("Expected %v got %v for %v", expected, dst.Elem(), exp)
+		}
+	}
+
+	_, err := Encode(tagDuplicate{})
+	if err == nil {
+		t.Errorf("Expected an error for duplicate positions")
+	}
+}
+
+func TestStructTagsScan(t *testing.T) {
+	var keyed tagKeyed
+	err := NewList(
+		NewList(Identifier("port"), Number("8080")),
+		NewList(Identifier("unknown"), Number("1")),
+		NewList(Identifier("HOST"), String("y")),
+	).Scan(&keyed)
+	if err != nil || keyed.Host != "y" || keyed.Port != 8080 {
+		t.Errorf("Expected {y 8080} got %v, %v", keyed, err)
+	}
+
+	var positions tagPositions
+	err = NewList(Number("1"), Number("2")).Scan(&positions)
+	if err != nil || positions != (tagPositions{A: 1, B: 2}) {
+		t.Errorf("Expected {1 0 0 2} got %v, %v", positions, err)
+	}
+}
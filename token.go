@@ -0,0 +1,96 @@
+package s
+
+import (
+	"fmt"
+	"io"
+)
+
+type (
+	// TokenKind identifies the kind of a Token.
+	TokenKind int
+	// Token is a lexical token of the input. Value holds the expression of
+	// an atom or comment, its type giving the kind of atom, and Text the
+	// source of the other tokens.
+	Token struct {
+		Kind       TokenKind
+		Value      Expression
+		Text       string
+		Start, End Position
+	}
+	// Tokenizer reads the input one token at a time, leaving it to the
+	// caller to build any structure. The embedded Reader's settings apply,
+	// except that comments are returned unless Comments is cleared.
+	// Limits.MaxListLength isn't enforced since lists aren't kept.
+	Tokenizer struct {
+		*Reader
+		opens []Token
+	}
+)
+
+const (
+	// TokenEOF ends the input
+	TokenEOF TokenKind = iota
+	// TokenOpenList opens a list with (, a vector with #( or a byte vector
+	// with #u8(
+	TokenOpenList
+	// TokenCloseList closes the innermost open list
+	TokenCloseList
+	// TokenAtom is any expression read whole, such as an identifier,
+	// number, string or character
+	TokenAtom
+	// TokenComment is a ; line comment or #| block comment |#
+	TokenComment
+	// TokenDatumComment is the #; which comments out the following datum
+	TokenDatumComment
+	// TokenQuote is one of the prefixes ' ` , and ,@ abbreviating a quote
+	// of the following datum
+	TokenQuote
+	// TokenDot separates the tail of a dotted list
+	TokenDot
+)
+
+var tokenKinds = []string{"EOF", "OpenList", "CloseList", "Atom", "Comment", "DatumComment", "Quote", "Dot"}
+
+func (this TokenKind) String() string {
+	if this < 0 || int(this) >= len(tokenKinds) {
+		return fmt.Sprintf("TokenKind(%d)", int(this))
+	}
+	return tokenKinds[this]
+}
+
+func NewTokenizer(reader io.Reader) *Tokenizer {
+	rdr := NewReader(reader)
+	rdr.Comments = true
+	return &Tokenizer{Reader: rdr}
+}
+
+// Next returns the next token. Unbalanced parentheses are syntax errors,
+// and once the input ends every call returns a TokenEOF.
+func (this *Tokenizer) Next() (Token, error) {
+	tok, err := this.readToken()
+	if err != nil {
+		return Token{}, err
+	}
+	if max := this.Limits.MaxDepth; max > 0 && len(this.opens) > max && tok.Kind != TokenCloseList && tok.Kind != TokenEOF {
+		return Token{}, &LimitError{Position: tok.Start, Limit: int64(max), Err: ErrMaxDepth}
+	}
+	switch tok.Kind {
+	case TokenOpenList:
+		this.opens = append(this.opens, tok)
+	case TokenCloseList:
+		if len(this.opens) == 0 {
+			return Token{}, this.syntaxError(tok.Start, ")", "Unknown token", nil)
+		}
+		this.opens = this.opens[:len(this.opens)-1]
+	case TokenEOF:
+		if n := len(this.opens); n > 0 {
+			return Token{}, this.syntaxError(this.opens[n-1].Start, this.opens[n-1].Text, "Unterminated list", io.EOF)
+		}
+	}
+	return tok, nil
+}
+
+// Depth returns the number of lists open after the last token.
+func (this *Tokenizer) Depth() int {
+	return len(this.opens)
+}
@@ -0,0 +1,92 @@
+package s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	input := "(a #(1 \"b\") ; c\n '#u8(2) . #;x #\\y)"
+	type token struct {
+		kind  TokenKind
+		value Expression
+		text  string
+		start int
+	}
+	expected := []token{
+		{TokenOpenList, nil, "(", 0},
+		{TokenAtom, Identifier("a"), "", 1},
+		{TokenOpenList, nil, "#(", 3},
+		{TokenAtom, Number("1"), "", 5},
+		{TokenAtom, String("b"), "", 7},
+		{TokenCloseList, nil, ")", 10},
+		{TokenComment, Comment(" c"), "", 12},
+		{TokenQuote, nil, "'", 17},
+		{TokenOpenList, nil, "#u8(", 18},
+		{TokenAtom, Number("2"), "", 22},
+		{TokenCloseList, nil, ")", 23},
+		{TokenDot, nil, ".", 25},
+		{TokenDatumComment, nil, "#;", 27},
+		{TokenAtom, Identifier("x"), "", 29},
+		{TokenAtom, Char('y'), "", 31},
+		{TokenCloseList, nil, ")", 34},
+		{TokenEOF, nil, "", 35},
+	}
+	tokenizer := NewTokenizer(bytes.NewBufferString(input))
+	for i, e := range expected {
+		tok, err := tokenizer.Next()
+		if err != nil {
+			t.Fatalf("Expected no error got %v for token %v", err, i)
+		}
+		got := token{tok.Kind, tok.Value, tok.Text, tok.Start.Offset}
+		if !reflect.DeepEqual(got, e) {
+			t.Errorf("Expected %v %#v %q at %v got %v %#v %q at %v", e.kind, e.value, e.text, e.start, got.kind, got.value, got.text, got.start)
+		}
+	}
+	if tokenizer.Depth() != 0 {
+		t.Errorf("Expected depth 0 got %v", tokenizer.Depth())
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	testCases := map[string]string{
+		"(a":   "Unterminated list",
+		"a )":  "Unknown token",
+		"(#z)": "Unknown token",
+	}
+	for input, msg := range testCases {
+		tokenizer := NewTokenizer(bytes.NewBufferString(input))
+		var err error
+		for err == nil {
+			var tok Token
+			tok, err = tokenizer.Next()
+			if tok.Kind == TokenEOF && err == nil {
+				err = io.EOF
+			}
+		}
+		if serr, ok := err.(*SyntaxError); !ok || serr.Msg != msg {
+			t.Errorf("Expected %v got %v for %q", msg, err, input)
+		}
+	}
+
+	tokenizer := NewTokenizer(bytes.NewBufferString("((a))"))
+	tokenizer.Limits = Limits{MaxDepth: 1}
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		_, err = tokenizer.Next()
+	}
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("Expected %v got %v", ErrMaxDepth, err)
+	}
+
+	tokenizer = NewTokenizer(bytes.NewBufferString("; a\n b"))
+	tokenizer.Comments = false
+	tok, err := tokenizer.Next()
+	if err != nil || fmt.Sprint(tok.Value) != "b" {
+		t.Errorf("Expected b got %v, %v", tok.Value, err)
+	}
+}
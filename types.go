@@ -16,19 +16,88 @@ type (
 	String     string
 	Number     string
 	Identifier string
-	True       struct{}
-	False      struct{}
+	// Vector is a fixed-size sequence written as #(a b c)
+	Vector []Expression
+	// ByteVector is a sequence of bytes written as #u8(1 2 3)
+	ByteVector []byte
+	// Keyword is a self-evaluating name such as :host, stored without its
+	// colon. Readers only produce keywords when Reader.Keywords is set.
+	Keyword string
+	// Char is a character written as #\a, #\space or #\x41
+	Char  rune
+	True  struct{}
+	False struct{}
+	// Pair is the cons cell of a dotted list such as (a . b). Proper lists
+	// are always List.
+	Pair struct {
+		Car, Cdr Expression
+	}
+	// comments are only produced when Reader.Comments is set
+	Comment      string
+	BlockComment string
+	DatumComment struct {
+		Datum Expression
+	}
 )
 
+var abbreviations = map[string]string{
+	"'":  "quote",
+	"`":  "quasiquote",
+	",":  "unquote",
+	",@": "unquote-splicing",
+}
+
 func NewList(expressions ...Expression) List {
 	return List(expressions)
 }
 
+// Cons returns car prepended to cdr. The result is a List when cdr is a
+// List and a Pair otherwise.
+func Cons(car, cdr Expression) Expression {
+	if lst, ok := cdr.(List); ok {
+		return lst.Prepend(car)
+	}
+	return Pair{car, cdr}
+}
+
+func isComment(exp Expression) bool {
+	switch exp.(type) {
+	case Comment, BlockComment, DatumComment:
+		return true
+	}
+	return false
+}
+
 func (this Binary) String() string {
 	var buf bytes.Buffer
 	this.Write(&buf)
 	return buf.String()
 }
+func (this BlockComment) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
+func (this ByteVector) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
+func (this Char) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
+func (this Comment) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
+func (this DatumComment) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
 func (this Identifier) String() string {
 	var buf bytes.Buffer
 	this.Write(&buf)
@@ -39,6 +108,11 @@ func (this False) String() string {
 	this.Write(&buf)
 	return buf.String()
 }
+func (this Keyword) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
 func (this List) Head() (Expression, error) {
 	if len(this) == 0 {
 		return nil, fmt.Errorf("Empty List")
@@ -57,11 +131,49 @@ func (this List) Prepend(exp Expression) List {
 func (this List) Append(exp Expression) List {
 	return List(append(this, exp))
 }
+
+// abbreviation returns the prefix of quote forms such as (quote x).
+func (this List) abbreviation() (string, bool) {
+	if len(this) != 2 {
+		return "", false
+	}
+	head, ok := this[0].(Identifier)
+	if !ok {
+		return "", false
+	}
+	for prefix, name := range abbreviations {
+		if string(head) == name {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// WithoutComments returns the list with any comment expressions removed.
+func (this List) WithoutComments() List {
+	for i, exp := range this {
+		if isComment(exp) {
+			lst := append(List{}, this[:i]...)
+			for _, exp := range this[i+1:] {
+				if !isComment(exp) {
+					lst = append(lst, exp)
+				}
+			}
+			return lst
+		}
+	}
+	return this
+}
 func (this List) String() string {
 	var buf bytes.Buffer
 	this.Write(&buf)
 	return buf.String()
 }
+func (this Pair) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
 func (this String) String() string {
 	var buf bytes.Buffer
 	this.Write(&buf)
@@ -72,3 +184,8 @@ func (this True) String() string {
 	this.Write(&buf)
 	return buf.String()
 }
+func (this Vector) String() string {
+	var buf bytes.Buffer
+	this.Write(&buf)
+	return buf.String()
+}
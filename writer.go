@@ -5,38 +5,103 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"strconv"
+	"unicode"
 	"unicode/utf8"
 )
 
-var hex = "0123456789abcdef"
+var hexDigits = "0123456789abcdef"
 
+// Write writes the binary as #b followed by base64. The rare values whose
+// base64 would read back as a binary number, such as the bytes d3 4d 34,
+// are written as #b|base64| instead.
 func (this Binary) Write(dst io.Writer) (err error) {
-	_, err = io.WriteString(dst, "#b")
-	if err != nil {
-		return
+	str := base64.StdEncoding.EncodeToString(this)
+	if isNumeric("#b" + str) {
+		str = "|" + str + "|"
+	}
+	_, err = io.WriteString(dst, "#b"+str)
+	return
+}
+func (this BlockComment) Write(dst io.Writer) (err error) {
+	_, err = io.WriteString(dst, "#|"+string(this)+"|#")
+	return
+}
+func (this ByteVector) Write(dst io.Writer) (err error) {
+	exps := make([]Expression, len(this))
+	for i, b := range this {
+		exps[i] = Number(strconv.Itoa(int(b)))
+	}
+	return writeList(dst, "#u8(", exps)
+}
+func (this Char) Write(dst io.Writer) (err error) {
+	str := `#\`
+	if name, ok := charName(this); ok {
+		str += name
+	} else if r := rune(this); unicode.IsGraphic(r) && !unicode.IsSpace(r) {
+		str += string(r)
+	} else {
+		str += "x" + strconv.FormatInt(int64(r), 16)
 	}
-	enc := base64.NewEncoder(base64.StdEncoding, dst)
-	_, err = enc.Write([]byte(this))
+	_, err = io.WriteString(dst, str)
+	return
+}
+func (this Comment) Write(dst io.Writer) (err error) {
+	_, err = io.WriteString(dst, ";"+string(this)+"\n")
+	return
+}
+func (this DatumComment) Write(dst io.Writer) (err error) {
+	_, err = io.WriteString(dst, "#;")
 	if err != nil {
 		return
 	}
-	err = enc.Close()
-	return
+	return this.Datum.Write(dst)
 }
 func (this False) Write(dst io.Writer) (err error) {
 	_, err = io.WriteString(dst, "#f")
 	return
 }
+
+// Write writes the identifier as is when it reads back as the same
+// identifier and as a |quoted| symbol otherwise.
 func (this Identifier) Write(dst io.Writer) (err error) {
+	if !isPlainIdentifier(string(this)) {
+		return writeQuoted(dst, string(this), '|')
+	}
 	_, err = io.WriteString(dst, string(this))
 	return
 }
+
+// Write writes the keyword with a leading colon, so it reads back as a
+// keyword only with the LeadingColon style. Names which need quoting are
+// quoted along with the colon, as in |:a b|.
+func (this Keyword) Write(dst io.Writer) (err error) {
+	str := ":" + string(this)
+	if isPlainIdentifier(str) {
+		_, err = io.WriteString(dst, str)
+		return
+	}
+	return writeQuoted(dst, str, '|')
+}
 func (this List) Write(dst io.Writer) (err error) {
-	_, err = io.WriteString(dst, "(")
+	if prefix, ok := this.abbreviation(); ok {
+		_, err = io.WriteString(dst, prefix)
+		if err != nil {
+			return
+		}
+		return this[1].Write(dst)
+	}
+
+	return writeList(dst, "(", this)
+}
+
+// writeList writes exps separated by spaces between open and ).
+func writeList(dst io.Writer, open string, exps []Expression) (err error) {
+	_, err = io.WriteString(dst, open)
 	if err != nil {
 		return
 	}
-	for i, exp := range this {
+	for i, exp := range exps {
 		if i > 0 {
 			_, err = io.WriteString(dst, " ")
 			if err != nil {
@@ -55,17 +120,69 @@ func (this Number) Write(dst io.Writer) (err error) {
 	_, err = io.WriteString(dst, string(this))
 	return
 }
+func (this Pair) Write(dst io.Writer) (err error) {
+	_, err = io.WriteString(dst, "(")
+	if err != nil {
+		return
+	}
+	var exp Expression = this
+	for first := true; ; first = false {
+		pair, ok := exp.(Pair)
+		if !ok {
+			break
+		}
+		if !first {
+			_, err = io.WriteString(dst, " ")
+			if err != nil {
+				return
+			}
+		}
+		err = pair.Car.Write(dst)
+		if err != nil {
+			return
+		}
+		exp = pair.Cdr
+	}
+	if lst, ok := exp.(List); ok {
+		// (a . (b c)) is the same as (a b c)
+		for _, e := range lst {
+			_, err = io.WriteString(dst, " ")
+			if err == nil {
+				err = e.Write(dst)
+			}
+			if err != nil {
+				return
+			}
+		}
+	} else {
+		_, err = io.WriteString(dst, " . ")
+		if err != nil {
+			return
+		}
+		err = exp.Write(dst)
+		if err != nil {
+			return
+		}
+	}
+	_, err = io.WriteString(dst, ")")
+	return
+}
 func (this String) Write(dst io.Writer) (err error) {
+	return writeQuoted(dst, string(this), '"')
+}
+
+// writeQuoted writes s between quote characters, escaping the quote,
+// backslashes and control characters.
+func writeQuoted(dst io.Writer, s string, quote byte) (err error) {
 	w := bufio.NewWriter(dst)
-	err = w.WriteByte('"')
+	err = w.WriteByte(quote)
 	if err != nil {
 		return
 	}
-	s := string(this)
 	start := 0
 	for i := 0; i < len(s); {
 		if b := s[i]; b < utf8.RuneSelf {
-			if 0x20 <= b && b != '\\' && b != '"' {
+			if 0x20 <= b && b != '\\' && b != quote {
 				i++
 				continue
 			}
@@ -76,14 +193,16 @@ func (this String) Write(dst io.Writer) (err error) {
 				}
 			}
 			switch b {
-			case '\\', '"':
+			case '\\', quote:
 				_, err = w.Write([]byte{'\\', b})
 			case '\n':
 				_, err = w.Write([]byte{'\\', 'n'})
 			case '\r':
 				_, err = w.Write([]byte{'\\', 'r'})
+			case '\t':
+				_, err = w.Write([]byte{'\\', 't'})
 			default:
-				_, err = w.Write([]byte{'\\', 'u', '0', '0', hex[b>>4], hex[b&0xF]})
+				_, err = w.Write([]byte{'\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF]})
 			}
 			if err != nil {
 				return
@@ -105,13 +224,16 @@ func (this String) Write(dst io.Writer) (err error) {
 			return
 		}
 	}
-	err = w.WriteByte('"')
+	err = w.WriteByte(quote)
 	if err != nil {
 		return
 	}
 	err = w.Flush()
 	return
 }
+func (this Vector) Write(dst io.Writer) (err error) {
+	return writeList(dst, "#(", this)
+}
 func (this True) Write(dst io.Writer) (err error) {
 	_, err = io.WriteString(dst, "#t")
 	return
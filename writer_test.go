@@ -2,7 +2,12 @@ package s
 
 import (
 	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
 	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 func TestWriters(t *testing.T) {
@@ -20,7 +25,15 @@ func TestWriters(t *testing.T) {
 		{String(`"a"`), `"\"a\""`},
 		{String("\n"), `"\n"`},
 		{String("\r"), `"\r"`},
+		{String("\t\x01é"), `"\t\u0001é"`},
 		{True{}, `#t`},
+		{NewList(Char('a'), Char(' '), Char(0), Char('\u00a0'), Char('λ')), `(#\a #\space #\null #\xa0 #\λ)`},
+		{NewList(Identifier("a"), Comment(" b"), Identifier("c")), "(a ; b\n c)"},
+		{BlockComment(" a "), `#| a |#`},
+		{Pair{Identifier("a"), Identifier("b")}, `(a . b)`},
+		{Pair{Number("1"), Pair{Number("2"), Identifier("c")}}, `(1 2 . c)`},
+		{Pair{Number("1"), NewList(Number("2"))}, `(1 2)`},
+		{DatumComment{NewList(Identifier("a"))}, `#;(a)`},
 	}
 
 	for _, c := range cases {
@@ -35,3 +48,101 @@ func TestWriters(t *testing.T) {
 		}
 	}
 }
+
+func TestBinaryRoundTrip(t *testing.T) {
+	// every 3 bytes whose base64 reads as a binary number, such as 0101
+	// or 1/1+
+	digits := "01+/"
+	inputs := []Binary{{}, {0}, {0xd3, 0x4d, 0x34, 0xd3, 0x4d, 0x34}}
+	for i := 0; i < 256; i++ {
+		encoded := []byte{digits[i&3], digits[i>>2&3], digits[i>>4&3], digits[i>>6&3]}
+		bs, err := base64.StdEncoding.DecodeString(string(encoded))
+		if err != nil {
+			t.Fatalf("Expected no error got %v for %s", err, encoded)
+		}
+		inputs = append(inputs, Binary(bs))
+	}
+	for _, bs := range inputs {
+		var buf bytes.Buffer
+		bs.Write(&buf)
+		exp, err := Read(&buf)
+		if err != nil || !reflect.DeepEqual(exp, bs) {
+			t.Errorf("Expected %x got %#v, %v", []byte(bs), exp, err)
+		}
+	}
+
+	_, err := Read(bytes.NewBufferString("#b|MDAw"))
+	if serr, ok := err.(*SyntaxError); !ok || serr.Msg != "Unterminated binary" {
+		t.Errorf("Expected an unterminated binary got %v", err)
+	}
+}
+
+func TestIdentifierRoundTrip(t *testing.T) {
+	type testCase struct {
+		Identifier
+		Result string
+	}
+	testCases := []testCase{
+		{"abc", "abc"},
+		{"λx", "λx"},
+		{"-", "-"},
+		{"...", "..."},
+		{"a b", "|a b|"},
+		{"(x)", "|(x)|"},
+		{"", "||"},
+		{"123", "|123|"},
+		{"+5", "|+5|"},
+		{".", "|.|"},
+		{"#t", "|#t|"},
+		{"a|b\\c", `|a\|b\\c|`},
+		{"tab\t", `|tab\t|`},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		err := NewList(tc.Identifier).Write(&buf)
+		if err != nil || buf.String() != "("+tc.Result+")" {
+			t.Errorf("Expected (%v) got %v, %v", tc.Result, buf.String(), err)
+			continue
+		}
+		exp, err := Read(&buf)
+		if err != nil || !reflect.DeepEqual(exp, NewList(tc.Identifier)) {
+			t.Errorf("Expected %q to round trip got %#v, %v", tc.Identifier, exp, err)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	const chunk = 1024
+	for first := rune(0); first <= utf8.MaxRune; first += chunk {
+		var raw, escaped bytes.Buffer
+		for r := first; r < first+chunk && r <= utf8.MaxRune; r++ {
+			if utf16.IsSurrogate(r) {
+				continue
+			}
+			raw.WriteRune(r)
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				fmt.Fprintf(&escaped, `\u%04x\u%04X`, r1, r2)
+			} else if r%2 == 0 {
+				fmt.Fprintf(&escaped, `\u%04X`, r)
+			} else {
+				fmt.Fprintf(&escaped, `\x%x;`, r)
+			}
+		}
+		expected := String(raw.String())
+
+		var buf bytes.Buffer
+		err := expected.Write(&buf)
+		if err != nil {
+			t.Fatalf("Expected no error got %v writing runes from %U", err, first)
+		}
+		for _, input := range []string{buf.String(), `"` + escaped.String() + `"`} {
+			exp, err := Read(bytes.NewBufferString(input))
+			if err != nil {
+				t.Fatalf("Expected no error got %v reading runes from %U", err, first)
+			}
+			if exp != expected {
+				t.Fatalf("Expected runes from %U to round trip", first)
+			}
+		}
+	}
+}